@@ -1,23 +1,298 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"mosoteach/internal/config"
 	"mosoteach/internal/web"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
+	configPath := flag.String("config", os.Getenv("MOSO_CONFIG"), "配置文件路径，状态文件和密钥文件放在同一目录（默认 ./user_data.json）")
+	listen := flag.String("listen", "", "Web 服务监听地址，如 :11451、127.0.0.1:8080")
+	overrides := make(map[string]string)
+	flag.Func("set", "覆盖配置项，如 -set user_name=xxx -set models.0.api_key=sk-xxx（可重复）", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return errors.New("格式应为 配置项=值")
+		}
+		overrides[key] = val
+		return nil
+	})
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: mosoteach [参数] [命令]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "命令:")
+		fmt.Fprintln(os.Stderr, "  (无)           启动 Web 服务")
+		fmt.Fprintln(os.Stderr, "  rotate-key     更换加密敏感字段使用的密钥（-passphrase 改用口令）")
+		fmt.Fprintln(os.Stderr, "  export         导出配置包（export -h 查看参数）")
+		fmt.Fprintln(os.Stderr, "  import         导入配置包，先显示差异（import -h 查看参数）")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "参数:")
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "配置优先级: 命令行参数 > 环境变量（如 MOSO_USER、MOSO_MODELS_0_API_KEY、MOSO_LISTEN） > 配置文件 > 默认值")
+	}
+	flag.Parse()
+
+	// 进程级的默认配置，各组件通过构造函数接收
 	cfg := config.GetConfig()
-	if err := cfg.Load(); err != nil {
+	if *configPath != "" {
+		cfg.SetFilePath(*configPath)
+	}
+	if *listen != "" {
+		overrides["listen"] = *listen
+	}
+	cfg.SetFlagOverrides(overrides)
+	if err := loadConfig(cfg); err != nil {
 		fmt.Printf("错误: 加载配置失败: %v\n", err)
-		fmt.Println("请确保 user_data.json 文件存在且格式正确")
+		fmt.Printf("请确保 %s 格式正确，或从 %s.bak.* 备份中恢复\n", cfg.FilePath, cfg.FilePath)
 		os.Exit(1)
 	}
 
-	server := web.NewServer()
-	if err := server.Start(11451); err != nil {
+	switch flag.Arg(0) {
+	case "":
+	case "rotate-key":
+		if err := rotateKey(cfg, flag.Args()[1:]); err != nil {
+			fmt.Printf("错误: 更换密钥失败: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "export":
+		if err := exportConfig(cfg, flag.Args()[1:]); err != nil {
+			fmt.Printf("错误: 导出配置失败: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "import":
+		if err := importConfig(cfg, flag.Args()[1:]); err != nil {
+			fmt.Printf("错误: 导入配置失败: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	server := web.NewServer(cfg)
+
+	// 配置文件被修改后自动重新加载
+	go cfg.Watch(context.Background(), config.DefaultWatchInterval)
+
+	// 退出时关闭常驻浏览器，避免残留 Chrome 进程
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		server.Close()
+		os.Exit(0)
+	}()
+
+	if err := server.Start(cfg.GetListen()); err != nil {
 		fmt.Printf("错误: 启动服务器失败: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// loadConfig 加载配置，敏感字段使用口令加密时提示输入口令
+func loadConfig(cfg *config.Config) error {
+	err := cfg.Load()
+	if !errors.Is(err, config.ErrPassphraseRequired) {
+		return err
+	}
+
+	passphrase, err := readLine("请输入配置口令: ")
+	if err != nil {
+		return err
+	}
+	cfg.SetPassphrase(passphrase)
+	return cfg.Load()
+}
+
+// rotateKey 更换密钥并重新加密配置和状态文件
+func rotateKey(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	usePassphrase := fs.Bool("passphrase", false, "改用口令加密（启动时需要输入口令或设置 MOSO_PASSPHRASE）")
+	fs.Parse(args)
+
+	var passphrase string
+	if *usePassphrase {
+		var err error
+		if passphrase, err = readLine("请输入新口令: "); err != nil {
+			return err
+		}
+		confirm, err := readLine("请再次输入新口令: ")
+		if err != nil {
+			return err
+		}
+		if passphrase != confirm {
+			return errors.New("两次输入的口令不一致")
+		}
+		if passphrase == "" {
+			return errors.New("口令不能为空")
+		}
+	}
+
+	newKey, err := cfg.RotateKey(passphrase)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case passphrase != "":
+		fmt.Println("已改用口令加密，启动时请输入新口令")
+	case newKey != "":
+		fmt.Println("已更换密钥，请将环境变量 MOSO_SECRET_KEY 更新为:")
+		fmt.Println(newKey)
+	default:
+		fmt.Printf("已更换密钥，新密钥保存在 %s，旧密钥已删除\n", cfg.KeyPath)
+	}
+	return nil
+}
+
+// exportConfig 导出配置包到文件
+func exportConfig(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	sections := fs.String("sections", "", "导出的内容，逗号分隔: "+strings.Join(config.BundleSections, ",")+"（默认全部）")
+	secrets := fs.String("secrets", config.SecretsRedact, "敏感字段的处理方式: redact（清空）、plain（明文）、encrypt（口令加密）")
+	output := fs.String("o", "mosoteach-config-"+time.Now().Format("20060102-150405")+".json", "输出文件")
+	fs.Parse(args)
+
+	opts := config.ExportOptions{Secrets: *secrets}
+	if *sections != "" {
+		opts.Sections = strings.Split(*sections, ",")
+	}
+	if *secrets == config.SecretsEncrypt {
+		passphrase, err := readLine("请输入配置包口令: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := readLine("请再次输入配置包口令: ")
+		if err != nil {
+			return err
+		}
+		if passphrase != confirm {
+			return errors.New("两次输入的口令不一致")
+		}
+		opts.Passphrase = passphrase
+	}
+
+	bundle, err := cfg.Export(opts)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(bundle, "", "    ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if err := os.WriteFile(*output, data, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("已导出 %s 到 %s\n", strings.Join(bundle.Sections, "、"), *output)
+	return nil
+}
+
+// importConfig 导入配置包，先显示差异，-dry-run 时不修改配置
+func importConfig(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	sections := fs.String("sections", "", "导入的内容，逗号分隔（默认配置包中的全部内容）")
+	dryRun := fs.Bool("dry-run", false, "只显示差异，不修改配置")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: mosoteach import [参数] <配置包文件>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	bundle, err := config.ParseBundle(data)
+	if err != nil {
+		return err
+	}
+
+	opts := config.ImportOptions{DryRun: true}
+	if *sections != "" {
+		opts.Sections = strings.Split(*sections, ",")
+	}
+	if bundle.Encrypted() {
+		if opts.Passphrase, err = readLine("请输入配置包口令: "); err != nil {
+			return err
+		}
+	}
+
+	changes, err := cfg.Import(bundle, opts)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("配置没有变化")
+		return nil
+	}
+	for _, c := range changes {
+		note := ""
+		switch c.Overridden {
+		case config.SourceEnv:
+			note = "（当前值来自环境变量，导入的值只写入配置文件）"
+		case config.SourceFlag:
+			note = "（当前值来自命令行参数，导入的值只写入配置文件）"
+		}
+		switch c.Action {
+		case "add":
+			fmt.Printf("+ %s = %s%s\n", c.Field, c.New, note)
+		case "remove":
+			fmt.Printf("- %s = %s%s\n", c.Field, c.Old, note)
+		default:
+			fmt.Printf("~ %s: %s → %s%s\n", c.Field, c.Old, c.New, note)
+		}
+	}
+	if *dryRun {
+		fmt.Printf("共 %d 项修改（未应用）\n", len(changes))
+		return nil
+	}
+
+	answer, err := readLine(fmt.Sprintf("应用以上 %d 项修改？[y/N] ", len(changes)))
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		fmt.Println("已取消")
+		return nil
+	}
+	opts.DryRun = false
+	if _, err := cfg.Import(bundle, opts); err != nil {
+		return err
+	}
+	fmt.Printf("已导入，配置保存在 %s\n", cfg.FilePath)
+	return nil
+}
+
+// stdin 多次读取口令时共用缓冲，避免丢失已读入缓冲区的输入
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine 从标准输入读取一行（口令或确认）
+func readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("读取口令失败: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -87,7 +87,7 @@ if command -v "$BINARY_NAME" &> /dev/null; then
     echo "运行方式:"
     echo "  $BINARY_NAME"
     echo ""
-    echo "然后访问 http://localhost:11451"
+    echo "然后访问 http://localhost:11451（可用 -listen 参数修改端口）"
 else
     echo ""
     echo "安装完成，但 $BINARY_NAME 不在 PATH 中"
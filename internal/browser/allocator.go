@@ -0,0 +1,223 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"mosoteach/internal/config"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	healthCheckTimeout = 5 * time.Second // 远程浏览器健康检查超时
+	connectAttempts    = 3               // 启动/连接浏览器的最大尝试次数
+	connectRetryDelay  = 2 * time.Second // 重试间隔
+)
+
+// AllocatorStrategy 浏览器分配策略：决定使用本地启动的 Chrome 还是连接远程 DevTools 端点
+type AllocatorStrategy interface {
+	// Name 策略描述，用于日志
+	Name() string
+	// HealthCheck 启动前检查浏览器是否可用
+	HealthCheck(ctx context.Context) error
+	// NewAllocator 创建 chromedp 分配器上下文
+	NewAllocator(parent context.Context) (context.Context, context.CancelFunc)
+	// NewTab 在分配器中创建标签页上下文
+	NewTab(allocCtx context.Context) (context.Context, context.CancelFunc)
+}
+
+// newAllocatorStrategy 根据配置选择分配策略，配置了远程地址时优先使用远程浏览器
+// debug 为 true 时本地浏览器以有界面模式启动（无图形界面时 DevTools 监听本机端口）
+func newAllocatorStrategy(cfg *config.Config, debug bool) AllocatorStrategy {
+	if browserURL := cfg.GetBrowserURL(); browserURL != "" {
+		return &remoteStrategy{url: browserURL, account: cfg.AccountID()}
+	}
+	path := cfg.GetChromePath()
+	if path == "" {
+		path = findChrome()
+	}
+	strategy := &localStrategy{binaryPath: path, debug: debug}
+	// 无法显示窗口时 DevTools 只监听本机的空闲端口，由 Web 服务器在认证后代理访问
+	if debug && !hasDisplay() {
+		if port, err := freePort(); err == nil {
+			strategy.debugPort = port
+		}
+	}
+	return strategy
+}
+
+// localStrategy 在本机启动 Chrome
+// 每次启动都使用 chromedp 创建的临时用户目录，不同账号的 Cookie 和缓存互不影响
+type localStrategy struct {
+	binaryPath string // 为空时由 chromedp 在 PATH 中查找
+	debug      bool   // 调试模式
+	debugPort  int    // 无图形界面调试时 DevTools 监听的本机端口
+}
+
+func (s *localStrategy) Name() string {
+	if s.binaryPath == "" {
+		return "本地 Chrome"
+	}
+	return "本地 Chrome (" + s.binaryPath + ")"
+}
+
+func (s *localStrategy) HealthCheck(ctx context.Context) error {
+	if s.binaryPath == "" {
+		return nil
+	}
+	if _, err := os.Stat(s.binaryPath); err != nil {
+		return fmt.Errorf("Chrome 不可用: %w", err)
+	}
+	return nil
+}
+
+func (s *localStrategy) NewAllocator(parent context.Context) (context.Context, context.CancelFunc) {
+	headless := !s.debug || !hasDisplay()
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", headless),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-dev-shm-usage", true),
+		chromedp.Flag("disable-blink-features", "AutomationControlled"),
+		chromedp.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"),
+	)
+
+	// 调试模式但无法显示窗口时，DevTools 监听本机端口，通过 Web 服务器查看
+	if s.debugPort != 0 {
+		opts = append(opts,
+			chromedp.Flag("remote-debugging-address", "127.0.0.1"),
+			chromedp.Flag("remote-debugging-port", strconv.Itoa(s.debugPort)),
+		)
+	}
+
+	// 设置Chrome路径
+	if s.binaryPath != "" {
+		opts = append(opts, chromedp.ExecPath(s.binaryPath))
+	}
+	return chromedp.NewExecAllocator(parent, opts...)
+}
+
+func (s *localStrategy) NewTab(allocCtx context.Context) (context.Context, context.CancelFunc) {
+	return chromedp.NewContext(allocCtx)
+}
+
+// remoteStrategy 连接已在运行的 Chrome（如独立的浏览器容器）
+// 支持 ws://host:9222/devtools/browser/... 或 http://host:9222 形式的地址
+// 默认账号使用浏览器的默认上下文，其他账号各自使用独立的浏览器上下文（相当于隐身窗口），Cookie 互不影响
+type remoteStrategy struct {
+	url     string
+	account string // 账号ID
+}
+
+func (s *remoteStrategy) Name() string {
+	return "远程 Chrome (" + s.url + ")"
+}
+
+// HealthCheck 请求 /json/version 确认 DevTools 端点可访问
+func (s *remoteStrategy) HealthCheck(ctx context.Context) error {
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return fmt.Errorf("远程浏览器地址无效: %w", err)
+	}
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return fmt.Errorf("远程浏览器地址缺少端口: %s", s.url)
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	versionURL := url.URL{Scheme: "http", Host: u.Host, Path: "/json/version"}
+	req, err := http.NewRequestWithContext(checkCtx, "GET", versionURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("无法连接远程浏览器: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("远程浏览器健康检查失败，状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *remoteStrategy) NewAllocator(parent context.Context) (context.Context, context.CancelFunc) {
+	return chromedp.NewRemoteAllocator(parent, s.url)
+}
+
+func (s *remoteStrategy) NewTab(allocCtx context.Context) (context.Context, context.CancelFunc) {
+	if s.account == config.DefaultAccount {
+		return chromedp.NewContext(allocCtx)
+	}
+	return chromedp.NewContext(allocCtx, chromedp.WithNewBrowserContext())
+}
+
+// findChrome 按平台查找本机 Chrome 可执行文件
+func findChrome() string {
+	var paths []string
+	switch runtime.GOOS {
+	case "windows":
+		paths = []string{
+			// 常见安装路径
+			os.Getenv("PROGRAMFILES") + "\\Google\\Chrome\\Application\\chrome.exe",
+			os.Getenv("PROGRAMFILES(X86)") + "\\Google\\Chrome\\Application\\chrome.exe",
+			os.Getenv("LOCALAPPDATA") + "\\Google\\Chrome\\Application\\chrome.exe",
+			// 便携版常见路径
+			".\\chrome-win64\\chrome.exe",
+			"..\\chrome-win64\\chrome.exe",
+		}
+	case "linux":
+		paths = []string{
+			"/usr/bin/google-chrome",
+			"/usr/bin/google-chrome-stable",
+			"/usr/bin/chromium-browser",
+			"/usr/bin/chromium",
+			"/snap/bin/chromium",
+		}
+	default:
+		// 其他平台交给 chromedp 自行查找
+		return ""
+	}
+
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// allocate 通过策略创建浏览器标签页并完成首次连接，失败时按间隔重试
+func allocate(strategy AllocatorStrategy) (allocCtx context.Context, allocCancel context.CancelFunc, tabCtx context.Context, tabCancel context.CancelFunc, err error) {
+	for attempt := 1; attempt <= connectAttempts; attempt++ {
+		if attempt > 1 {
+			fmt.Printf("[DEBUG] 第 %d 次尝试连接浏览器...\n", attempt)
+			time.Sleep(connectRetryDelay)
+		}
+
+		if err = strategy.HealthCheck(context.Background()); err != nil {
+			continue
+		}
+
+		allocCtx, allocCancel = strategy.NewAllocator(context.Background())
+		tabCtx, tabCancel = strategy.NewTab(allocCtx)
+
+		// 首次 Run 会真正启动/连接浏览器，不能带超时，否则超时后整个浏览器会被关闭
+		if err = chromedp.Run(tabCtx); err != nil {
+			tabCancel()
+			allocCancel()
+			continue
+		}
+		return allocCtx, allocCancel, tabCtx, tabCancel, nil
+	}
+	return nil, nil, nil, nil, fmt.Errorf("启动浏览器失败（%s）: %w", strategy.Name(), err)
+}
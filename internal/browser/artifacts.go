@@ -0,0 +1,195 @@
+package browser
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	runsDir         = "./runs" // 运行产物根目录
+	maxConsoleLines = 500      // 最多保留的控制台消息条数
+)
+
+var (
+	runIDPattern    = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+	unsafeNameChars = regexp.MustCompile(`[\\/:*?"<>|\s]+`)
+)
+
+// Artifact 运行产物文件
+type Artifact struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// NewRunID 生成运行 ID（时间戳 + 随机后缀）
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// RunArtifactDir 获取账号某次运行的产物目录（runs/<账号ID>/<运行ID>），ID 不合法时返回错误
+func RunArtifactDir(accountID, runID string) (string, error) {
+	if !runIDPattern.MatchString(accountID) {
+		return "", fmt.Errorf("无效的账号ID: %s", accountID)
+	}
+	if !runIDPattern.MatchString(runID) {
+		return "", fmt.Errorf("无效的运行ID: %s", runID)
+	}
+	return filepath.Join(runsDir, accountID, runID), nil
+}
+
+// ListArtifacts 列出账号指定运行的产物文件
+func ListArtifacts(accountID, runID string) ([]Artifact, error) {
+	dir, err := RunArtifactDir(accountID, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Artifact{}, nil
+		}
+		return nil, err
+	}
+
+	artifacts := make([]Artifact, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		artifacts = append(artifacts, Artifact{
+			Name:    entry.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].Name < artifacts[j].Name
+	})
+	return artifacts, nil
+}
+
+// SetRunID 设置运行 ID，失败产物会保存到对应目录
+func (b *BrowserExecutor) SetRunID(runID string) {
+	b.runID = runID
+}
+
+// RunID 获取运行 ID（未设置时在首次保存产物时自动生成）
+func (b *BrowserExecutor) RunID() string {
+	return b.runID
+}
+
+// listenConsole 记录页面控制台消息和未捕获异常
+func (b *BrowserExecutor) listenConsole() {
+	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			parts := make([]string, 0, len(ev.Args))
+			for _, arg := range ev.Args {
+				if len(arg.Value) > 0 {
+					parts = append(parts, string(arg.Value))
+				} else if arg.Description != "" {
+					parts = append(parts, arg.Description)
+				}
+			}
+			b.appendConsole(fmt.Sprintf("[%s] %s", ev.Type, strings.Join(parts, " ")))
+		case *runtime.EventExceptionThrown:
+			msg := ev.ExceptionDetails.Text
+			if ev.ExceptionDetails.Exception != nil && ev.ExceptionDetails.Exception.Description != "" {
+				msg += " " + ev.ExceptionDetails.Exception.Description
+			}
+			b.appendConsole("[exception] " + msg)
+		}
+	})
+}
+
+// appendConsole 追加一条控制台消息
+func (b *BrowserExecutor) appendConsole(line string) {
+	b.consoleMu.Lock()
+	defer b.consoleMu.Unlock()
+
+	b.consoleLogs = append(b.consoleLogs, time.Now().Format("15:04:05")+" "+line)
+	if len(b.consoleLogs) > maxConsoleLines {
+		b.consoleLogs = b.consoleLogs[len(b.consoleLogs)-maxConsoleLines:]
+	}
+}
+
+// captureFailure 保存失败现场：整页截图、页面 HTML、控制台消息和当前 URL
+// 返回保存的 HTML 文件路径
+func (b *BrowserExecutor) captureFailure(stage string, cause error) (string, error) {
+	if b.runID == "" {
+		b.runID = NewRunID()
+	}
+	dir, err := RunArtifactDir(b.cfg.AccountID(), b.runID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	b.failureSeq++
+	prefix := filepath.Join(dir, fmt.Sprintf("%02d-%s", b.failureSeq, unsafeNameChars.ReplaceAllString(stage, "_")))
+
+	var (
+		location   string
+		html       string
+		screenshot []byte
+	)
+	// 页面可能已崩溃，逐项采集，失败的项跳过
+	if err := chromedp.Run(b.ctx, chromedp.Location(&location)); err != nil {
+		b.logDebug("获取当前URL失败: %v", err)
+	}
+	if err := chromedp.Run(b.ctx, chromedp.OuterHTML(`html`, &html, chromedp.ByQuery)); err != nil {
+		b.logDebug("获取页面HTML失败: %v", err)
+	}
+	if err := chromedp.Run(b.ctx, chromedp.FullScreenshot(&screenshot, 90)); err != nil {
+		b.logDebug("截图失败: %v", err)
+	}
+
+	b.consoleMu.Lock()
+	consoleText := strings.Join(b.consoleLogs, "\n")
+	b.consoleMu.Unlock()
+
+	errText := ""
+	if cause != nil {
+		errText = cause.Error()
+	}
+	info := fmt.Sprintf("time: %s\nstage: %s\nurl: %s\nerror: %s\n",
+		time.Now().Format("2006-01-02 15:04:05"), stage, location, errText)
+
+	htmlPath := prefix + ".html"
+	files := map[string][]byte{
+		prefix + ".txt":         []byte(info),
+		htmlPath:                []byte(html),
+		prefix + "-console.log": []byte(consoleText),
+	}
+	if len(screenshot) > 0 {
+		files[prefix+".png"] = screenshot
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	b.logf("已保存失败现场: %s", dir)
+	return htmlPath, nil
+}
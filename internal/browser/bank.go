@@ -0,0 +1,108 @@
+package browser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mosoteach/internal/state"
+	"sort"
+	"strings"
+	"time"
+)
+
+// questionKey 生成题目指纹（题型 + 题干 + 排序后的选项内容），选项顺序被打乱时仍能命中
+func questionKey(q Question) string {
+	texts := make([]string, len(q.Options))
+	for i, opt := range q.Options {
+		texts[i] = strings.TrimSpace(opt.Text)
+	}
+	sort.Strings(texts)
+
+	h := sha256.New()
+	h.Write([]byte(string(q.Type)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.TrimSpace(q.Content)))
+	for _, t := range texts {
+		h.Write([]byte{0})
+		h.Write([]byte(t))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// encodeBankAnswer 将答案转换为题库存储格式：选择题保存选项内容而不是字母
+func encodeBankAnswer(q Question, answer string) string {
+	if q.Type == QuestionTypeFill {
+		return answer
+	}
+
+	var texts []string
+	for _, label := range strings.Split(answer, ",") {
+		label = strings.ToUpper(strings.TrimSpace(label))
+		for _, opt := range q.Options {
+			if strings.EqualFold(opt.Label, label) {
+				texts = append(texts, strings.TrimSpace(opt.Text))
+				break
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// decodeBankAnswer 将题库中的答案还原为当前页面的选项字母，有选项找不到时返回空
+func decodeBankAnswer(q Question, stored string) string {
+	if q.Type == QuestionTypeFill || stored == "" {
+		return stored
+	}
+
+	var labels []string
+	for _, text := range strings.Split(stored, "\n") {
+		found := false
+		for _, opt := range q.Options {
+			if strings.TrimSpace(opt.Text) == text {
+				labels = append(labels, opt.Label)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ""
+		}
+	}
+	return strings.Join(labels, ",")
+}
+
+// lookupBank 从题库缓存中查找答案，返回命中的题目数量
+// 题库中的答案大多来自以往 AI 的作答，提交前无法确认对错，调用方需在日志中说明未经验证
+func (b *BrowserExecutor) lookupBank(questions []Question, answers []string) int {
+	hits := 0
+	for i, q := range questions {
+		if answers[i] != "" {
+			continue
+		}
+		stored, ok := b.cfg.LookupAnswer(questionKey(q))
+		if !ok {
+			continue
+		}
+		if answer := decodeBankAnswer(q, stored.Answer); answer != "" {
+			answers[i] = answer
+			hits++
+		}
+	}
+	return hits
+}
+
+// saveToBank 将本次 AI 给出的答案写入题库缓存，提交前无法确认对错，保存为未验证
+func (b *BrowserExecutor) saveToBank(questions []Question, answers []string) {
+	now := time.Now().Unix()
+	entries := make(map[string]state.BankAnswer)
+	for i, q := range questions {
+		if i >= len(answers) || answers[i] == "" {
+			continue
+		}
+		if stored := encodeBankAnswer(q, answers[i]); stored != "" {
+			entries[questionKey(q)] = state.BankAnswer{Answer: stored, SavedAt: now}
+		}
+	}
+	if err := b.cfg.SaveAnswers(entries); err != nil {
+		b.logDebug("保存题库缓存失败: %v", err)
+	}
+}
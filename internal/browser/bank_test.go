@@ -0,0 +1,77 @@
+package browser
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mosoteach/internal/config"
+	"mosoteach/internal/models"
+)
+
+// newTestExecutor 使用临时目录中的配置创建执行器
+func newTestExecutor(t *testing.T) *BrowserExecutor {
+	t.Helper()
+	t.Setenv("MOSO_PASSPHRASE", "")
+	t.Setenv("MOSO_SECRET_KEY", "")
+	cfg := config.New()
+	cfg.SetFilePath(filepath.Join(t.TempDir(), "user_data.json"))
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return &BrowserExecutor{cfg: cfg, modelManager: models.NewModelManager(cfg), ctx: context.Background()}
+}
+
+func TestFastModeUsesBank(t *testing.T) {
+	questions := []Question{
+		{Type: QuestionTypeSingle, Content: "1+1=?", Options: []Option{{Label: "A", Text: "1"}, {Label: "B", Text: "2"}}},
+		{Type: QuestionTypeFill, Content: "中国的首都是____"},
+	}
+
+	tests := []struct {
+		name      string
+		remaining time.Duration // 测验剩余时间，为零时不限时
+		want      []string
+	}{
+		{name: "时间不足时使用题库中的答案", remaining: time.Minute, want: []string{"B", "北京"}},
+		// 没有配置模型，请求 AI 得不到答案
+		{name: "时间充足时请求 AI", want: []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestExecutor(t)
+			// 题库中的答案来自以往 AI 的作答，都未经验证
+			b.saveToBank(questions, []string{"B", "北京"})
+			if tt.remaining > 0 {
+				b.deadline = time.Now().Add(tt.remaining)
+			}
+
+			answers, err := b.getBatchAnswers(context.Background(), questions, "测验", 1, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range tt.want {
+				if answers[i] != want {
+					t.Errorf("answers[%d] = %q, want %q", i, answers[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupBankShuffledOptions(t *testing.T) {
+	b := newTestExecutor(t)
+	saved := Question{Type: QuestionTypeMultiple, Content: "选出偶数", Options: []Option{{Label: "A", Text: "1"}, {Label: "B", Text: "2"}, {Label: "C", Text: "4"}}}
+	b.saveToBank([]Question{saved}, []string{"B,C"})
+
+	shuffled := Question{Type: QuestionTypeMultiple, Content: "选出偶数", Options: []Option{{Label: "A", Text: "4"}, {Label: "B", Text: "1"}, {Label: "C", Text: "2"}}}
+	answers := []string{""}
+	if hits := b.lookupBank([]Question{shuffled}, answers); hits != 1 || answers[0] != "C,A" {
+		t.Errorf("lookupBank = %d, %q, want 1, %q", hits, answers[0], "C,A")
+	}
+	if answer, ok := b.cfg.LookupAnswer(questionKey(saved)); !ok || answer.Verified {
+		t.Errorf("LookupAnswer = %+v, %v, 应保存为未验证", answer, ok)
+	}
+}
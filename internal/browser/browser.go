@@ -9,22 +9,24 @@ import (
 	"mosoteach/internal/processor"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 )
 
 const (
-	loginURL = "https://www.mosoteach.cn/web/index.php?c=passport&m=index"
+	loginURL  = "https://www.mosoteach.cn/web/index.php?c=passport&m=index"
+	courseURL = "https://www.mosoteach.cn/web/index.php?c=clazzcourse&m=index"
 
 	// 时间常量
-	loginWaitTime     = 10 * time.Second // 登录后等待时间
-	pageLoadWaitTime  = 3 * time.Second  // 页面加载等待时间
-	elementWaitTime   = 2 * time.Second  // 元素等待时间
-	shortWaitTime     = 500 * time.Millisecond
-	browserTimeout    = 30 * time.Minute  // 浏览器总超时
-	apiRequestTimeout = 180 * time.Second // AI API 请求超时
+	loginWaitTime    = 10 * time.Second // 登录后等待时间
+	pageLoadWaitTime = 3 * time.Second  // 页面加载等待时间
+	elementWaitTime  = 2 * time.Second  // 元素等待时间
+	shortWaitTime    = 500 * time.Millisecond
+	browserTimeout   = 30 * time.Minute // 浏览器总超时
 
 	// 批量处理常量
 	batchSize = 10 // 每批处理的题目数量
@@ -76,27 +78,47 @@ type BrowserExecutor struct {
 	cancel        context.CancelFunc
 	timeoutCancel context.CancelFunc // 超时取消函数（独立保存）
 	callback      ProgressCallback
+	session       *SessionManager // 可选：复用的长期浏览器会话
+	releaseOnce   *sync.Once      // 保证会话只归还一次
+	stopOnce      sync.Once       // 停止任务和运行结束可能同时调用 Stop
+	started       bool            // 已启动浏览器，调用方可以提前 Start 以便立即知道会话是否被占用
+	runID         string          // 运行 ID，失败产物按运行分目录保存
+	failureSeq    int             // 本次运行中的失败序号
+	consoleMu     sync.Mutex
+	consoleLogs   []string // 页面控制台消息
+	stepper       *Stepper // 调试模式单步控制器
+	timerMu       sync.Mutex
+	deadline      time.Time // 当前测验的截止时间（无时间限制时为零值）
+	fastMode      bool      // 时间不足，已切换到快速模式
 }
 
 // NewBrowserExecutor 创建浏览器执行器
-func NewBrowserExecutor() *BrowserExecutor {
-	cfg := config.GetConfig()
+func NewBrowserExecutor(cfg *config.Config) *BrowserExecutor {
 	return &BrowserExecutor{
 		cfg:          cfg,
-		modelManager: models.NewModelManager(),
+		modelManager: models.NewModelManager(cfg),
 	}
 }
 
 // NewBrowserExecutorWithCallback 创建带回调的浏览器执行器
-func NewBrowserExecutorWithCallback(callback ProgressCallback) *BrowserExecutor {
-	cfg := config.GetConfig()
+func NewBrowserExecutorWithCallback(cfg *config.Config, callback ProgressCallback) *BrowserExecutor {
 	return &BrowserExecutor{
 		cfg:          cfg,
-		modelManager: models.NewModelManager(),
+		modelManager: models.NewModelManager(cfg),
 		callback:     callback,
 	}
 }
 
+// NewBrowserExecutorWithSession 创建复用浏览器会话的执行器
+func NewBrowserExecutorWithSession(cfg *config.Config, session *SessionManager, callback ProgressCallback) *BrowserExecutor {
+	return &BrowserExecutor{
+		cfg:          cfg,
+		modelManager: models.NewModelManager(cfg),
+		callback:     callback,
+		session:      session,
+	}
+}
+
 // sendProgress 发送进度事件
 func (b *BrowserExecutor) sendProgress(eventType, message string, progress, total int) {
 	b.sendFullProgress(eventType, message, progress, total, "", 0, 0)
@@ -135,33 +157,71 @@ func (b *BrowserExecutor) logf(format string, args ...interface{}) {
 	b.logInfo(format, args...)
 }
 
-// Start 启动浏览器
-func (b *BrowserExecutor) Start() error {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", true), // 测试无头模式
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.Flag("disable-blink-features", "AutomationControlled"),
-		chromedp.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"),
-	)
+// Start 启动浏览器，使用共享会话时 ctx 用于等待其他任务归还标签页，已启动时直接返回
+func (b *BrowserExecutor) Start(ctx context.Context) error {
+	if b.started {
+		return nil
+	}
 
-	// 设置Chrome路径
-	if b.cfg.ChromeBinaryPath != "" {
-		opts = append(opts, chromedp.ExecPath(b.cfg.ChromeBinaryPath))
+	// 使用共享会话时只需借用已有的标签页
+	if b.session != nil {
+		tabCtx, err := b.session.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		b.releaseOnce = &sync.Once{}
+		b.ctx, b.timeoutCancel = context.WithTimeout(tabCtx, browserTimeout)
+		b.listenConsole()
+		b.started = true
+		return nil
 	}
 
-	b.allocCtx, b.allocCancel = chromedp.NewExecAllocator(context.Background(), opts...)
-	b.ctx, b.cancel = chromedp.NewContext(b.allocCtx)
+	strategy := newAllocatorStrategy(b.cfg, b.stepper != nil)
+	b.logDebug("使用%s", strategy.Name())
+	if b.stepper != nil {
+		if addr := inspectorAddr(strategy); addr != "" {
+			b.stepper.setInspectorAddr(addr)
+			b.logf("调试模式: 可在网页的调试面板中打开 DevTools 查看浏览器页面")
+		}
+	}
+
+	var err error
+	b.allocCtx, b.allocCancel, b.ctx, b.cancel, err = allocate(strategy)
+	if err != nil {
+		return err
+	}
 
 	// 设置超时（保存超时取消函数，避免覆盖原始 cancel）
 	b.ctx, b.timeoutCancel = context.WithTimeout(b.ctx, browserTimeout)
+	b.listenConsole()
+	b.started = true
 
 	return nil
 }
 
-// Stop 关闭浏览器
+// Stop 关闭浏览器，可以重复调用
 func (b *BrowserExecutor) Stop() {
+	b.stopOnce.Do(b.stop)
+}
+
+// stop Stop 的实现
+func (b *BrowserExecutor) stop() {
+	b.modelManager.Close()
+
+	// 使用共享会话时不关闭浏览器，只中断当前操作并归还会话
+	if b.session != nil {
+		if b.releaseOnce != nil {
+			b.releaseOnce.Do(func() {
+				if b.timeoutCancel != nil {
+					b.timeoutCancel()
+				}
+				b.session.Release()
+				b.logDebug("已归还浏览器会话")
+			})
+		}
+		return
+	}
+
 	b.logDebug("正在关闭浏览器...")
 
 	// 先取消超时 context
@@ -189,23 +249,72 @@ func (b *BrowserExecutor) Stop() {
 }
 
 // Login 登录并保存Cookie
+// 失败时返回 *LoginError，可用 errors.Is 判断失败类型
 func (b *BrowserExecutor) Login() error {
+	// 共享会话仍有效时跳过登录
+	if b.session != nil && b.session.IsLoggedIn() {
+		b.logf("复用已登录的浏览器会话")
+		return nil
+	}
+
+	// 优先尝试使用保存的Cookie恢复登录
+	if b.restoreCookies() {
+		if err := b.saveCookies(); err != nil {
+			b.logf("警告: 保存Cookie失败: %v", err)
+		}
+		if b.session != nil {
+			b.session.SetLoggedIn(true)
+		}
+		b.logf("已使用保存的Cookie恢复登录")
+		return nil
+	}
+
+	return b.formLogin()
+}
+
+// ForceLogin 清除浏览器中的Cookie后用账号密码重新登录，不复用已有的会话和保存的Cookie
+func (b *BrowserExecutor) ForceLogin() error {
+	if b.session != nil {
+		b.session.SetLoggedIn(false)
+	}
+	if err := chromedp.Run(b.ctx, network.ClearBrowserCookies()); err != nil {
+		return b.loginFailure(classifyRunError(err), err.Error())
+	}
+	return b.formLogin()
+}
+
+// formLogin 填写登录表单登录并保存Cookie
+func (b *BrowserExecutor) formLogin() error {
 	b.logf("正在登录...")
 
-	err := chromedp.Run(b.ctx,
+	if err := chromedp.Run(b.ctx,
 		chromedp.Navigate(loginURL),
 		chromedp.Sleep(elementWaitTime),
-		chromedp.WaitVisible(`#account-name`, chromedp.ByID),
-		chromedp.SendKeys(`#account-name`, b.cfg.UserData.UserName, chromedp.ByID),
+	); err != nil {
+		return b.loginFailure(classifyRunError(err), err.Error())
+	}
+
+	// 等待登录表单出现，设置较短超时
+	waitCtx, waitCancel := context.WithTimeout(b.ctx, loginWaitTime)
+	defer waitCancel()
+	if err := chromedp.Run(waitCtx, chromedp.WaitVisible(`#account-name`, chromedp.ByID)); err != nil {
+		return b.loginFailure(ErrUnknownPage, "未找到登录表单")
+	}
+
+	account := b.cfg.GetUserData()
+	if err := chromedp.Run(b.ctx,
+		chromedp.SendKeys(`#account-name`, account.UserName, chromedp.ByID),
 		chromedp.Sleep(shortWaitTime),
-		chromedp.SendKeys(`#user-pwd`, b.cfg.UserData.Password, chromedp.ByID),
+		chromedp.SendKeys(`#user-pwd`, account.Password, chromedp.ByID),
 		chromedp.Sleep(1*time.Second),
 		chromedp.Click(`#login-button-1`, chromedp.ByID),
-		chromedp.Sleep(loginWaitTime),
-	)
+	); err != nil {
+		return b.loginFailure(classifyRunError(err), err.Error())
+	}
 
-	if err != nil {
-		return fmt.Errorf("登录失败: %w", err)
+	// 检测登录结果（错误提示、验证码、跳转）
+	if err := b.waitLoginResult(); err != nil {
+		return err
 	}
 
 	// 提取并保存Cookie（参照Python: driver.get_cookies()）
@@ -213,10 +322,67 @@ func (b *BrowserExecutor) Login() error {
 		b.logf("警告: 保存Cookie失败: %v", err)
 	}
 
+	if b.session != nil {
+		b.session.SetLoggedIn(true)
+	}
+
 	b.logf("登录成功!")
 	return nil
 }
 
+// restoreCookies 注入保存的Cookie并访问需要登录的页面，检查会话是否仍然有效
+func (b *BrowserExecutor) restoreCookies() bool {
+	saved := b.cfg.GetSavedCookies()
+	if len(saved) == 0 {
+		return false
+	}
+
+	if expiry := b.cfg.CookieExpiry(); !expiry.IsZero() {
+		b.logDebug("保存的Cookie最早将于 %s 过期", expiry.Format("2006-01-02 15:04:05"))
+	}
+
+	params := make([]*network.CookieParam, 0, len(saved))
+	for _, c := range saved {
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+		}
+		if c.SameSite != "" {
+			param.SameSite = network.CookieSameSite(c.SameSite)
+		}
+		if c.Expires > 0 {
+			expires := cdp.TimeSinceEpoch(time.Unix(int64(c.Expires), 0))
+			param.Expires = &expires
+		}
+		params = append(params, param)
+	}
+
+	var location string
+	var hasLoginForm bool
+	err := chromedp.Run(b.ctx,
+		network.SetCookies(params),
+		chromedp.Navigate(courseURL),
+		chromedp.Sleep(elementWaitTime),
+		chromedp.Location(&location),
+		chromedp.Evaluate(`!!document.querySelector('#account-name')`, &hasLoginForm),
+	)
+	if err != nil {
+		b.logDebug("使用保存的Cookie恢复登录失败: %v", err)
+		return false
+	}
+
+	// 未登录时会被重定向到登录页
+	if strings.Contains(location, "passport") || hasLoginForm {
+		b.logf("保存的Cookie已失效，使用账号密码登录")
+		return false
+	}
+	return true
+}
+
 // saveCookies 从浏览器提取Cookie并保存到配置文件
 func (b *BrowserExecutor) saveCookies() error {
 	var cookies []*network.Cookie
@@ -231,16 +397,27 @@ func (b *BrowserExecutor) saveCookies() error {
 		return fmt.Errorf("获取Cookie失败: %w", err)
 	}
 
-	// 转换为cookie字符串（参照Python: "; ".join([f"{cookie['name']}={cookie['value']}" for cookie in Cookies_list])）
-	var parts []string
+	// 保留作用域、过期时间和安全标记，以便下次直接注入浏览器
+	saved := make([]config.SavedCookie, 0, len(cookies))
 	for _, c := range cookies {
-		parts = append(parts, fmt.Sprintf("%s=%s", c.Name, c.Value))
+		expires := c.Expires
+		if c.Session {
+			expires = 0
+		}
+		saved = append(saved, config.SavedCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  expires,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: c.SameSite.String(),
+		})
 	}
-	cookieStr := strings.Join(parts, "; ")
 
 	// 保存到配置文件
-	b.cfg.UserData.Cookie = cookieStr
-	if err := b.cfg.Save(); err != nil {
+	if err := b.cfg.UpdateCookies(saved); err != nil {
 		return fmt.Errorf("保存配置失败: %w", err)
 	}
 
@@ -248,218 +425,6 @@ func (b *BrowserExecutor) saveCookies() error {
 	return nil
 }
 
-// FetchQuizzesByBrowser 通过浏览器获取题库列表
-func (b *BrowserExecutor) FetchQuizzesByBrowser() ([]processor.QuizInfo, error) {
-	return b.FetchQuizzesByBrowserWithContext(context.Background())
-}
-
-// FetchQuizzesByBrowserWithContext 通过浏览器获取题库列表（带context）
-func (b *BrowserExecutor) FetchQuizzesByBrowserWithContext(ctx context.Context) ([]processor.QuizInfo, error) {
-	const courseURL = "https://www.mosoteach.cn/web/index.php?c=clazzcourse&m=index"
-
-	// 检查是否已取消
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-	}
-
-	b.sendProgress("log", "正在获取课程列表...", 0, 0)
-
-	// 导航到课程列表页面
-	if err := chromedp.Run(b.ctx,
-		chromedp.Navigate(courseURL),
-		chromedp.Sleep(pageLoadWaitTime),
-	); err != nil {
-		return nil, fmt.Errorf("导航到课程页面失败: %w", err)
-	}
-
-	// 获取所有课程的ID和互动页面URL
-	var courseData []map[string]string
-	if err := chromedp.Run(b.ctx,
-		chromedp.Evaluate(`
-			Array.from(document.querySelectorAll('li.class-item')).map(li => ({
-				id: li.getAttribute('data-id') || '',
-				url: li.getAttribute('data-url') || '',
-				status: li.getAttribute('data-status') || '',
-				name: (li.querySelector('.class-info-subject') || {}).textContent || ''
-			})).filter(c => c.status === 'OPEN')
-		`, &courseData),
-	); err != nil {
-		return nil, fmt.Errorf("获取课程列表失败: %w", err)
-	}
-
-	b.logf("找到 %d 个开放课程", len(courseData))
-
-	// 临时存储题库信息（带确认页URL）
-	type tempQuizInfo struct {
-		ConfirmURL string
-		CourseID   string
-		CourseName string
-		QuizID     string
-		Name       string
-	}
-	var tempQuizzes []tempQuizInfo
-	seenQuizIDs := make(map[string]bool)
-
-	// 遍历每个课程获取题库
-	for i, course := range courseData {
-		// 检查是否已取消
-		select {
-		case <-ctx.Done():
-			b.sendProgress("log", "任务已取消", 0, 0)
-			return nil, ctx.Err()
-		default:
-		}
-
-		if course["url"] == "" {
-			continue
-		}
-
-		courseName := strings.TrimSpace(course["name"])
-		if courseName == "" {
-			courseName = "未命名课程"
-		}
-
-		b.sendProgress("progress", fmt.Sprintf("正在获取课程 %d/%d: %s", i+1, len(courseData), courseName), i+1, len(courseData))
-
-		// 导航到课程互动页面
-		if err := chromedp.Run(b.ctx,
-			chromedp.Navigate(course["url"]),
-			chromedp.Sleep(elementWaitTime),
-		); err != nil {
-			b.logf("导航到课程 %s 失败: %v", courseName, err)
-			continue
-		}
-
-		// 获取进行中的测验
-		var quizData []map[string]string
-		if err := chromedp.Run(b.ctx,
-			chromedp.Evaluate(`
-				Array.from(document.querySelectorAll('div.interaction-row')).map(row => ({
-					id: row.getAttribute('data-id') || '',
-					type: row.getAttribute('data-type') || '',
-					status: row.getAttribute('data-row-status') || '',
-					title: row.getAttribute('data-title') || ''
-				})).filter(q => q.type === 'QUIZ' && q.status === 'IN_PRGRS')
-			`, &quizData),
-		); err != nil {
-			b.logf("获取课程 %s 的题库失败: %v", courseName, err)
-			continue
-		}
-
-		for _, quiz := range quizData {
-			if quiz["id"] == "" {
-				continue
-			}
-
-			// 检查是否已存在（去重）
-			if seenQuizIDs[quiz["id"]] {
-				b.logf("  跳过重复题库: %s", quiz["title"])
-				continue
-			}
-			seenQuizIDs[quiz["id"]] = true
-
-			confirmURL := fmt.Sprintf("https://www.mosoteach.cn/web/index.php?c=interaction_quiz&m=start_quiz_confirm&clazz_course_id=%s&id=%s&order_item=group",
-				course["id"], quiz["id"])
-
-			tempQuizzes = append(tempQuizzes, tempQuizInfo{
-				ConfirmURL: confirmURL,
-				CourseID:   course["id"],
-				CourseName: courseName,
-				QuizID:     quiz["id"],
-				Name:       quiz["title"],
-			})
-			b.logf("  找到题库: %s (课程: %s)", quiz["title"], courseName)
-		}
-	}
-
-	b.sendProgress("log", fmt.Sprintf("共找到 %d 个题库，正在获取答题链接...", len(tempQuizzes)), 0, 0)
-
-	// 第二阶段：访问每个确认页面获取真正的答题URL
-	var allQuizzes []processor.QuizInfo
-	for i, tq := range tempQuizzes {
-		// 检查是否已取消
-		select {
-		case <-ctx.Done():
-			b.sendProgress("log", "任务已取消", 0, 0)
-			return nil, ctx.Err()
-		default:
-		}
-
-		b.sendProgress("progress", fmt.Sprintf("获取答题链接 %d/%d: %s", i+1, len(tempQuizzes), tq.Name), i+1, len(tempQuizzes))
-
-		// 导航到确认页面
-		if err := chromedp.Run(b.ctx,
-			chromedp.Navigate(tq.ConfirmURL),
-			chromedp.Sleep(elementWaitTime),
-		); err != nil {
-			b.logf("  导航到确认页面失败: %v", err)
-			continue
-		}
-
-		// 获取隐藏的真正答题URL
-		var hiddenURL string
-		if err := chromedp.Run(b.ctx,
-			chromedp.Evaluate(`
-				(function() {
-					var el = document.querySelector('div.hidden-box.hidden-url');
-					return el ? el.textContent.trim() : '';
-				})()
-			`, &hiddenURL),
-		); err != nil {
-			b.logf("  获取答题URL失败: %v", err)
-			continue
-		}
-
-		// 如果没找到，尝试从链接获取
-		if hiddenURL == "" {
-			var linkHref string
-			if err := chromedp.Run(b.ctx,
-				chromedp.Evaluate(`
-					(function() {
-						var a = document.querySelector('div.can-operate-color a');
-						return a ? a.href : '';
-					})()
-				`, &linkHref),
-			); err == nil && linkHref != "" {
-				// 访问链接页面
-				if err := chromedp.Run(b.ctx,
-					chromedp.Navigate(linkHref),
-					chromedp.Sleep(elementWaitTime),
-				); err == nil {
-					chromedp.Run(b.ctx,
-						chromedp.Evaluate(`
-							(function() {
-								var el = document.querySelector('div.hidden-box.hidden-url');
-								return el ? el.textContent.trim() : '';
-							})()
-						`, &hiddenURL),
-					)
-				}
-			}
-		}
-
-		if hiddenURL == "" {
-			b.logf("  未找到答题URL: %s", tq.Name)
-			continue
-		}
-
-		allQuizzes = append(allQuizzes, processor.QuizInfo{
-			URL:        hiddenURL,
-			CourseID:   tq.CourseID,
-			CourseName: tq.CourseName,
-			QuizID:     tq.QuizID,
-			Name:       tq.Name,
-			Completed:  b.cfg.IsURLCompleted(hiddenURL),
-		})
-		b.logf("  获取答题URL成功: %s", tq.Name)
-	}
-
-	b.sendProgress("log", fmt.Sprintf("共获取 %d 个有效答题链接", len(allQuizzes)), 0, 0)
-	return allQuizzes, nil
-}
-
 // processQuiz 处理单个测验（兼容旧调用）
 func (b *BrowserExecutor) processQuiz(quiz processor.QuizInfo) error {
 	return b.processQuizWithProgress(context.Background(), quiz, 1, 1)
@@ -482,6 +447,10 @@ func (b *BrowserExecutor) processQuizWithProgress(ctx context.Context, quiz proc
 	// 重置进度条（重要：切换题库时必须重置）
 	b.sendFullProgress("progress", fmt.Sprintf("正在加载: %s", quizName), 0, 0, quizName, quizProgress, quizTotal)
 
+	if err := b.step(ctx, PhaseNavigate); err != nil {
+		return err
+	}
+
 	// 导航到测验页面
 	err := chromedp.Run(b.ctx,
 		chromedp.Navigate(quiz.URL),
@@ -505,8 +474,7 @@ func (b *BrowserExecutor) processQuizWithProgress(ctx context.Context, quiz proc
 		strings.Contains(pageHTML, "pic_nothing") {
 		b.logf("【%s】已用尽作答机会，跳过", quizName)
 		// 标记为已完成，避免下次再尝试
-		b.cfg.AddCompletedURL(quiz.URL)
-		b.cfg.Save()
+		b.markCompleted(quiz)
 		return nil
 	}
 
@@ -514,8 +482,7 @@ func (b *BrowserExecutor) processQuizWithProgress(ctx context.Context, quiz proc
 	if strings.Contains(pageHTML, `class="blank"></div></div></div>`) ||
 		strings.Contains(pageHTML, `<div class="blank"></div>`) {
 		b.logf("【%s】页面为空白，可能无法作答，跳过", quizName)
-		b.cfg.AddCompletedURL(quiz.URL)
-		b.cfg.Save()
+		b.markCompleted(quiz)
 		return nil
 	}
 
@@ -539,13 +506,19 @@ func (b *BrowserExecutor) processQuizWithProgress(ctx context.Context, quiz proc
 			strings.Contains(pageHTML, "pic_nothing") ||
 			strings.Contains(pageHTML, "m-disable") {
 			b.logf("【%s】已用尽作答机会，跳过", quizName)
-			b.cfg.AddCompletedURL(quiz.URL)
-			b.cfg.Save()
+			b.markCompleted(quiz)
 			return nil
 		}
 		return fmt.Errorf("等待题目容器加载超时: %w", err)
 	}
 
+	// 读取倒计时（限时测验）
+	b.readTimeLimit(quizName)
+
+	if err := b.step(ctx, PhaseParse); err != nil {
+		return err
+	}
+
 	// 获取页面HTML
 	var htmlContent string
 	err = chromedp.Run(b.ctx,
@@ -555,11 +528,13 @@ func (b *BrowserExecutor) processQuizWithProgress(ctx context.Context, quiz proc
 		return fmt.Errorf("获取页面内容失败: %w", err)
 	}
 
-	// 解析题目
-	questions, err := b.parseQuestions(htmlContent)
+	// 解析题目（分页题库会逐页解析）
+	pagination := b.detectPagination()
+	pages, err := b.collectQuestionPages(pagination, htmlContent)
 	if err != nil {
 		return fmt.Errorf("解析题目失败: %w", err)
 	}
+	questions := flattenPages(pages)
 
 	if len(questions) == 0 {
 		b.sendFullProgress("log", "当前页面未找到题目", 0, 0, quizName, quizProgress, quizTotal)
@@ -569,15 +544,34 @@ func (b *BrowserExecutor) processQuizWithProgress(ctx context.Context, quiz proc
 	totalQuestions := len(questions)
 	b.sendFullProgress("progress", fmt.Sprintf("【%s】共 %d 题，正在获取答案...", quizName, totalQuestions), 0, totalQuestions, quizName, quizProgress, quizTotal)
 
-	// 批量获取所有题目的答案（一次API请求）
-	answers, err := b.getBatchAnswers(ctx, questions, quizName, quizProgress, quizTotal)
+	if err := b.step(ctx, PhaseAnswer); err != nil {
+		return err
+	}
+
+	// 批量获取所有题目的答案，限时测验在截止前预留提交时间
+	answerCtx, answerCancel := b.withAnswerDeadline(ctx)
+	answers, err := b.getBatchAnswers(answerCtx, questions, quizName, quizProgress, quizTotal)
+	answerCancel()
 	if err != nil {
 		// 如果是取消错误，直接返回
 		if ctx.Err() != nil {
 			b.sendProgress("log", "任务已取消", 0, 0)
 			return ctx.Err()
 		}
-		return fmt.Errorf("批量获取答案失败: %w", err)
+		// 作答时间用完：提交已获得的答案，避免超时后全部作废
+		if answerCtx.Err() == context.DeadlineExceeded {
+			b.logf("【%s】作答时间即将用完，已获得 %d/%d 题答案，立即提交", quizName, countNonEmpty(answers), totalQuestions)
+		} else {
+			return fmt.Errorf("批量获取答案失败: %w", err)
+		}
+	}
+	b.saveToBank(questions, answers)
+
+	// AI 没有给出答案的题目，用题库中未经验证的答案兜底，总比空着提交好
+	if countNonEmpty(answers) < totalQuestions {
+		if hits := b.lookupBank(questions, answers); hits > 0 {
+			b.logf("【%s】%d 道题未获得 AI 答案，使用题库中未经验证的答案", quizName, hits)
+		}
 	}
 
 	// 检查是否已取消
@@ -588,37 +582,53 @@ func (b *BrowserExecutor) processQuizWithProgress(ctx context.Context, quiz proc
 	default:
 	}
 
-	// 一次性批量填写所有答案（效率更高）
+	if err := b.step(ctx, PhaseFill); err != nil {
+		return err
+	}
+
+	// 批量填写答案（分页题库逐页填写，全部填完后再提交）
 	b.sendFullProgress("progress", fmt.Sprintf("【%s】正在批量填写 %d 题...", quizName, totalQuestions), 0, totalQuestions, quizName, quizProgress, quizTotal)
 
-	filledCount, err := b.batchSubmitAnswers(questions, answers)
+	filledCount, err := b.fillQuestionPages(pagination, pages, answers)
 	if err != nil {
 		b.logf("批量填写出错: %v", err)
 	}
 
 	b.sendFullProgress("progress", fmt.Sprintf("【%s】%d 题已填写完毕，正在提交...", quizName, filledCount), totalQuestions, totalQuestions, quizName, quizProgress, quizTotal)
 
+	if err := b.step(ctx, PhaseSubmit); err != nil {
+		return err
+	}
+
 	// 提交整个测验
 	return b.submitQuiz(quiz)
 }
 
 // parseQuestions 使用JavaScript在浏览器中直接获取题目信息（更可靠）
-func (b *BrowserExecutor) parseQuestions(htmlContent string) ([]Question, error) {
+// visibleOnly 为 true 时只解析当前页可见的题目（分页题库逐页解析时使用）
+func (b *BrowserExecutor) parseQuestions(htmlContent string, visibleOnly bool) ([]Question, error) {
 	// 使用 JavaScript 直接获取题目信息，参照 Python 的 XPath 逻辑
 	// Python XPath: //div[@class="t-con"]/div/div[@class="t-type SINGLE|MULTI|FILL"]
-	jsGetQuestions := `
+	jsGetQuestions := fmt.Sprintf(`
 	(function() {
 		var results = [];
+		var visibleOnly = %t;
+
+		// 分页题库只保留当前页可见的元素
+		function keep(list) {
+			var arr = Array.from(list);
+			return visibleOnly ? arr.filter(function(el) { return el.offsetParent !== null; }) : arr;
+		}
 
 		// 获取所有题型元素 - 使用精确的class匹配，与Python XPath一致
 		// class="t-type SINGLE" 或 "t-type MULTI" 或 "t-type FILL"
-		var typeElements = document.querySelectorAll('div[class="t-type SINGLE"], div[class="t-type MULTI"], div[class="t-type FILL"]');
+		var typeElements = keep(document.querySelectorAll('div[class="t-type SINGLE"], div[class="t-type MULTI"], div[class="t-type FILL"]'));
 
 		// 获取所有题干元素
-		var stemElements = document.querySelectorAll('div.t-subject.t-item');
+		var stemElements = keep(document.querySelectorAll('div.t-subject.t-item'));
 
 		// 获取所有选项区域
-		var optionBlocks = document.querySelectorAll('div.t-option.t-item');
+		var optionBlocks = keep(document.querySelectorAll('div.t-option.t-item'));
 
 		console.log('找到题型元素: ' + typeElements.length);
 		console.log('找到题干元素: ' + stemElements.length);
@@ -666,7 +676,7 @@ func (b *BrowserExecutor) parseQuestions(htmlContent string) ([]Question, error)
 
 		return JSON.stringify(results);
 	})()
-	`
+	`, visibleOnly)
 
 	var jsonResult string
 	err := chromedp.Run(b.ctx,
@@ -811,31 +821,57 @@ func cleanHTML(html string) string {
 }
 
 // getAnswerWithContext 带context获取单个题目答案
-func (b *BrowserExecutor) getAnswerWithContext(ctx context.Context, q Question) (string, error) {
+func (b *BrowserExecutor) getAnswerWithContext(ctx context.Context, q Question, fast bool) (string, error) {
 	prompt := fmt.Sprintf("%s\n%s", string(q.Type), q.Content)
 
 	for _, opt := range q.Options {
 		prompt += fmt.Sprintf("\n%s.%s", opt.Label, opt.Text)
 	}
 
-	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	reqCtx, cancel := context.WithTimeout(ctx, b.modelManager.Timeout(fast))
 	defer cancel()
 
-	return b.modelManager.GetAnswer(reqCtx, prompt)
+	return b.askModel(reqCtx, prompt, fast)
+}
+
+// askModel 请求模型答案，快速模式下只使用响应最快的模型
+func (b *BrowserExecutor) askModel(ctx context.Context, prompt string, fast bool) (string, error) {
+	if fast {
+		return b.modelManager.GetAnswerFastest(ctx, prompt)
+	}
+	return b.modelManager.GetAnswer(ctx, prompt)
 }
 
 // getBatchAnswers 批量获取所有题目的答案（分批请求，每批最多10道题）
+// 限时测验时间不足时切换到快速模式：优先使用题库缓存、加大批次、只用最快的模型
 func (b *BrowserExecutor) getBatchAnswers(ctx context.Context, questions []Question, quizName string, quizProgress, quizTotal int) ([]string, error) {
 	if len(questions) == 0 {
 		return []string{}, nil
 	}
 
 	allAnswers := make([]string, len(questions))
+	fast := b.checkTimeBudget(len(questions))
+	if fast {
+		if hits := b.lookupBank(questions, allAnswers); hits > 0 {
+			b.logf("题库缓存命中 %d 道题，使用以往未经验证的答案", hits)
+		}
+	}
 
-	totalBatches := (len(questions) + batchSize - 1) / batchSize
-	b.logf("共 %d 道题，分 %d 批处理", len(questions), totalBatches)
+	// 待请求 AI 的题目序号
+	var pending []int
+	for i := range questions {
+		if allAnswers[i] == "" {
+			pending = append(pending, i)
+		}
+	}
 
-	for batchStart := 0; batchStart < len(questions); batchStart += batchSize {
+	size := batchSize
+	if fast {
+		size = fastBatchSize
+	}
+	b.logf("共 %d 道题，需请求 %d 道，分 %d 批处理", len(questions), len(pending), (len(pending)+size-1)/size)
+
+	for len(pending) > 0 {
 		// 检查是否已取消
 		select {
 		case <-ctx.Done():
@@ -844,58 +880,76 @@ func (b *BrowserExecutor) getBatchAnswers(ctx context.Context, questions []Quest
 		default:
 		}
 
-		batchEnd := batchStart + batchSize
-		if batchEnd > len(questions) {
-			batchEnd = len(questions)
+		// 每批开始前重新评估剩余时间
+		if !fast && b.checkTimeBudget(len(pending)) {
+			fast = true
+			size = fastBatchSize
+			if hits := b.lookupBank(questions, allAnswers); hits > 0 {
+				b.logf("题库缓存命中 %d 道题，使用以往未经验证的答案", hits)
+				pending = unanswered(pending, allAnswers)
+				if len(pending) == 0 {
+					break
+				}
+			}
 		}
 
-		batchQuestions := questions[batchStart:batchEnd]
-		batchNum := batchStart/batchSize + 1
-		b.logf("正在处理第 %d/%d 批（题目 %d-%d）...", batchNum, totalBatches, batchStart+1, batchEnd)
+		end := size
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[:end]
+		pending = pending[end:]
+
+		batchQuestions := make([]Question, len(batch))
+		for k, idx := range batch {
+			batchQuestions[k] = questions[idx]
+		}
+		b.logf("正在处理 %d 道题（剩余 %d 道）...", len(batch), len(pending))
 
-		batchAnswers, err := b.getBatchAnswersForChunk(ctx, batchQuestions, batchStart)
+		batchAnswers, err := b.getBatchAnswersForChunk(ctx, batchQuestions, batch[0], fast)
 		if err != nil {
 			// 如果是取消错误，直接返回
 			if ctx.Err() != nil {
 				return allAnswers, ctx.Err()
 			}
-			b.logf("第 %d 批请求失败: %v，尝试逐题获取...", batchNum, err)
+			b.logf("本批请求失败: %v，尝试逐题获取...", err)
 			// 失败时降级为逐题获取
-			for i, q := range batchQuestions {
+			for k, q := range batchQuestions {
 				// 检查取消
 				select {
 				case <-ctx.Done():
 					return allAnswers, ctx.Err()
 				default:
 				}
-				answer, err := b.getAnswerWithContext(ctx, q)
+				answer, err := b.getAnswerWithContext(ctx, q, fast)
 				if err != nil {
 					if ctx.Err() != nil {
 						return allAnswers, ctx.Err()
 					}
-					b.logf("第 %d 题获取失败: %v", batchStart+i+1, err)
+					b.logf("第 %d 题获取失败: %v", batch[k]+1, err)
 					continue
 				}
-				allAnswers[batchStart+i] = answer
+				allAnswers[batch[k]] = answer
 			}
 			continue
 		}
 
 		// 将批次答案复制到总答案数组
-		for i, ans := range batchAnswers {
-			allAnswers[batchStart+i] = ans
+		for k, ans := range batchAnswers {
+			allAnswers[batch[k]] = ans
 			if ans != "" {
-				b.logDebug("  → 第%d题答案: %s", batchStart+i+1, ans)
+				b.logDebug("  → 第%d题答案: %s", batch[k]+1, ans)
 			} else {
-				b.logDebug("  → 第%d题答案: (空)", batchStart+i+1)
+				b.logDebug("  → 第%d题答案: (空)", batch[k]+1)
 			}
 		}
 
 		// 更新进度条
-		b.sendFullProgress("progress", fmt.Sprintf("【%s】正在处理...", quizName), batchEnd, len(questions), quizName, quizProgress, quizTotal)
+		done := len(questions) - len(pending)
+		b.sendFullProgress("progress", fmt.Sprintf("【%s】正在处理...", quizName), done, len(questions), quizName, quizProgress, quizTotal)
 
-		// 批次之间稍作延迟，避免请求过快
-		if batchEnd < len(questions) {
+		// 批次之间稍作延迟，避免请求过快（快速模式下不再等待）
+		if len(pending) > 0 && !fast {
 			time.Sleep(1 * time.Second)
 		}
 	}
@@ -904,8 +958,19 @@ func (b *BrowserExecutor) getBatchAnswers(ctx context.Context, questions []Quest
 	return allAnswers, nil
 }
 
-// getBatchAnswersForChunk 获取一批题目的答案
-func (b *BrowserExecutor) getBatchAnswersForChunk(ctx context.Context, questions []Question, startIndex int) ([]string, error) {
+// unanswered 过滤出仍没有答案的题目序号
+func unanswered(indexes []int, answers []string) []int {
+	var result []int
+	for _, idx := range indexes {
+		if answers[idx] == "" {
+			result = append(result, idx)
+		}
+	}
+	return result
+}
+
+// getBatchAnswersForChunk 获取一批题目的答案，fast 为 true 时只请求最快的模型
+func (b *BrowserExecutor) getBatchAnswersForChunk(ctx context.Context, questions []Question, startIndex int, fast bool) ([]string, error) {
 	// 统计题目类型
 	singleCount, multiCount, fillCount := 0, 0, 0
 	for _, q := range questions {
@@ -948,11 +1013,11 @@ func (b *BrowserExecutor) getBatchAnswersForChunk(ctx context.Context, questions
 		promptBuilder.WriteString(fmt.Sprintf("【答案%d】\n", i+1))
 	}
 
-	// 使用传入的 context，并添加超时
-	reqCtx, cancel := context.WithTimeout(ctx, apiRequestTimeout) // 每批180秒超时
+	// 使用传入的 context，超时由各模型配置的请求超时决定
+	reqCtx, cancel := context.WithTimeout(ctx, b.modelManager.Timeout(fast))
 	defer cancel()
 
-	response, err := b.modelManager.GetAnswer(reqCtx, promptBuilder.String())
+	response, err := b.askModel(reqCtx, promptBuilder.String(), fast)
 	if err != nil {
 		return nil, fmt.Errorf("批量请求失败: %w", err)
 	}
@@ -1026,7 +1091,8 @@ func countNonEmpty(answers []string) int {
 }
 
 // batchSubmitAnswers 一次性批量填写所有答案（高效模式）
-func (b *BrowserExecutor) batchSubmitAnswers(questions []Question, answers []string) (int, error) {
+// visibleOnly 为 true 时只在当前页可见的题目中按顺序填写
+func (b *BrowserExecutor) batchSubmitAnswers(questions []Question, answers []string, visibleOnly bool) (int, error) {
 	if len(questions) == 0 {
 		return 0, nil
 	}
@@ -1087,10 +1153,18 @@ func (b *BrowserExecutor) batchSubmitAnswers(questions []Question, answers []str
 	jsBatchFill := fmt.Sprintf(`
 		(async function() {
 			var answers = %s;
+			var visibleOnly = %t;
 			var filledCount = 0;
 			var debugLog = [];
-			var subjects = document.querySelectorAll('.t-subject.t-item');
-			var fillInputs = document.querySelectorAll('.tp-blank input.el-input__inner');
+
+			// 分页题库只在当前页可见的题目中定位
+			function keep(list) {
+				var arr = Array.from(list);
+				return visibleOnly ? arr.filter(function(el) { return el.offsetParent !== null; }) : arr;
+			}
+
+			var subjects = keep(document.querySelectorAll('.t-subject.t-item'));
+			var fillInputs = keep(document.querySelectorAll('.tp-blank input.el-input__inner'));
 
 			// 延迟函数
 			function sleep(ms) {
@@ -1218,7 +1292,7 @@ func (b *BrowserExecutor) batchSubmitAnswers(questions []Question, answers []str
 
 			return {count: filledCount, log: debugLog.join('|')};
 		})()
-	`, string(answerJSON))
+	`, string(answerJSON), visibleOnly)
 
 	var resultMap map[string]interface{}
 	err = chromedp.Run(b.ctx,
@@ -1254,8 +1328,14 @@ func (b *BrowserExecutor) batchSubmitAnswers(questions []Question, answers []str
 
 // submitQuiz 提交测验
 func (b *BrowserExecutor) submitQuiz(quiz processor.QuizInfo) error {
-	// 检查是否需要延迟提交
+	// 检查是否需要延迟提交（限时测验不能等到截止之后）
 	delay := b.cfg.GetSubmitDelay()
+	if deadline, ok := b.answerDeadline(); ok && delay > 0 {
+		if limit := int(time.Until(deadline).Seconds()); delay > limit {
+			delay = max(limit, 0)
+			b.logf("限时测验，提交延迟缩短为 %d 秒", delay)
+		}
+	}
 	if delay > 0 {
 		b.logf("等待 %d 秒后提交...", delay)
 		ticker := time.NewTicker(1 * time.Second)
@@ -1373,17 +1453,27 @@ func (b *BrowserExecutor) submitQuiz(quiz processor.QuizInfo) error {
 		chromedp.Sleep(3*time.Second),
 	)
 
-	// 记录已完成的URL
-	b.cfg.AddCompletedURL(quiz.URL)
-	b.cfg.MarkQuizCompleted(quiz.URL)
-	b.cfg.Save()
+	// 记录完成状态
+	b.markCompleted(quiz)
 
-	b.sendProgress("quiz_completed", quiz.URL, 0, 0)
+	b.sendFullProgress("quiz_completed", quiz.Name, 0, 0, quiz.Name, 0, 0)
 	b.logf("测验提交成功!")
 
 	return nil
 }
 
+// markCompleted 按 (课程ID, 题库ID) 记录题库已完成，避免下次再尝试
+func (b *BrowserExecutor) markCompleted(quiz processor.QuizInfo) {
+	if err := b.cfg.MarkQuizCompleted(config.CompletionRecord{
+		CourseID:   quiz.CourseID,
+		QuizID:     quiz.QuizID,
+		CourseName: quiz.CourseName,
+		Name:       quiz.Name,
+	}); err != nil {
+		b.logf("警告: 保存完成记录失败: %v", err)
+	}
+}
+
 // Run 运行自动答题
 func (b *BrowserExecutor) Run() error {
 	return b.RunWithContext(context.Background())
@@ -1392,7 +1482,7 @@ func (b *BrowserExecutor) Run() error {
 // RunWithContext 带context运行自动答题
 func (b *BrowserExecutor) RunWithContext(ctx context.Context) error {
 	// 启动浏览器
-	if err := b.Start(); err != nil {
+	if err := b.Start(ctx); err != nil {
 		return err
 	}
 	defer b.Stop()
@@ -1404,18 +1494,10 @@ func (b *BrowserExecutor) RunWithContext(ctx context.Context) error {
 		return err
 	}
 
-	// 重新加载配置（获取新Cookie）
-	b.cfg.Load()
-
 	b.sendProgress("log", "正在获取题库列表...", 0, 0)
 
-	// 获取待处理的测验（现在使用新的Cookie）
-	proc, err := processor.NewDataProcessor()
-	if err != nil {
-		return fmt.Errorf("创建数据处理器失败: %w", err)
-	}
-
-	quizzes, err := proc.FetchPendingQuizzes()
+	// 获取待处理的测验（现在使用新的Cookie，按课程筛选设置选择课程，置顶课程在前）
+	quizzes, err := b.DiscoverQuizzes(ctx, processor.DiscoverOptions{})
 	if err != nil {
 		return fmt.Errorf("获取测验列表失败: %w", err)
 	}
@@ -1433,14 +1515,14 @@ func (b *BrowserExecutor) RunWithContext(ctx context.Context) error {
 }
 
 // RunSingleQuiz 运行单个题库
-func (b *BrowserExecutor) RunSingleQuiz(ctx context.Context, quizURL string) error {
-	return b.RunMultipleQuizzes(ctx, []string{quizURL})
+func (b *BrowserExecutor) RunSingleQuiz(ctx context.Context, ref processor.QuizRef) error {
+	return b.RunMultipleQuizzes(ctx, []processor.QuizRef{ref})
 }
 
-// RunMultipleQuizzes 运行多个指定题库
-func (b *BrowserExecutor) RunMultipleQuizzes(ctx context.Context, quizURLs []string) error {
+// RunMultipleQuizzes 运行多个指定题库，答题地址在登录后重新获取
+func (b *BrowserExecutor) RunMultipleQuizzes(ctx context.Context, refs []processor.QuizRef) error {
 	// 启动浏览器
-	if err := b.Start(); err != nil {
+	if err := b.Start(ctx); err != nil {
 		return err
 	}
 	defer b.Stop()
@@ -1452,16 +1534,11 @@ func (b *BrowserExecutor) RunMultipleQuizzes(ctx context.Context, quizURLs []str
 		return err
 	}
 
-	// 重新加载配置
-	b.cfg.Load()
+	b.sendProgress("log", "正在获取选中题库的答题链接...", 0, 0)
 
-	// 构建题库列表
-	quizzes := make([]processor.QuizInfo, len(quizURLs))
-	for i, url := range quizURLs {
-		quizzes[i] = processor.QuizInfo{
-			URL:  url,
-			Name: fmt.Sprintf("选中题库 %d", i+1),
-		}
+	quizzes, err := processor.ResolveQuizzes(ctx, b, b.cfg, refs, b.reportDiscovery)
+	if err != nil {
+		return fmt.Errorf("获取答题链接失败: %w", err)
 	}
 
 	return b.ProcessQuizzesWithContext(ctx, quizzes)
@@ -1500,6 +1577,9 @@ func (b *BrowserExecutor) ProcessQuizzesWithContext(ctx context.Context, quizzes
 				return ctx.Err()
 			}
 			b.sendProgress("log", fmt.Sprintf("处理失败: %v", err), 0, 0)
+			if _, captureErr := b.captureFailure(quizName, err); captureErr != nil {
+				b.logDebug("保存失败现场失败: %v", captureErr)
+			}
 			continue
 		}
 
@@ -0,0 +1,176 @@
+package browser
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const debugStepDelay = 2 * time.Second // 调试模式下连续运行时每个阶段之间的停顿
+
+// 调试模式下的执行阶段
+const (
+	PhaseNavigate = "导航"
+	PhaseParse    = "解析题目"
+	PhaseAnswer   = "获取答案"
+	PhaseFill     = "填写答案"
+	PhaseSubmit   = "提交"
+)
+
+// DebugState 调试状态
+type DebugState struct {
+	Phase        string `json:"phase"`        // 当前（或即将执行的）阶段
+	Waiting      bool   `json:"waiting"`      // 是否正在等待用户操作
+	Continuous   bool   `json:"continuous"`   // 是否处于连续运行状态
+	InspectorURL string `json:"inspectorUrl"` // DevTools 地址（无图形界面时，由 Web 服务器代理）
+}
+
+// Stepper 调试单步控制器：在每个阶段前暂停，等待“下一步”或“继续”
+type Stepper struct {
+	mu            sync.Mutex
+	next          chan struct{}
+	phase         string
+	waiting       bool
+	continuous    bool
+	inspectorAddr string
+}
+
+// NewStepper 创建单步控制器（默认单步暂停）
+func NewStepper() *Stepper {
+	return &Stepper{next: make(chan struct{}, 1)}
+}
+
+// Wait 在进入阶段前调用：单步模式下阻塞直到 Next/Continue，连续模式下短暂停顿
+func (s *Stepper) Wait(ctx context.Context, phase string) error {
+	s.mu.Lock()
+	s.phase = phase
+	continuous := s.continuous
+	if !continuous {
+		s.waiting = true
+	}
+	s.mu.Unlock()
+
+	if continuous {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(debugStepDelay):
+			return nil
+		}
+	}
+
+	defer func() {
+		s.mu.Lock()
+		s.waiting = false
+		s.mu.Unlock()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.next:
+		return nil
+	}
+}
+
+// Next 执行下一个阶段后再次暂停
+func (s *Stepper) Next() {
+	select {
+	case s.next <- struct{}{}:
+	default:
+	}
+}
+
+// Continue 不再暂停，以放慢的速度连续运行
+func (s *Stepper) Continue() {
+	s.mu.Lock()
+	s.continuous = true
+	s.mu.Unlock()
+	s.Next()
+}
+
+// Pause 回到单步模式，在下一个阶段前暂停
+func (s *Stepper) Pause() {
+	s.mu.Lock()
+	s.continuous = false
+	s.mu.Unlock()
+}
+
+// State 获取当前调试状态
+func (s *Stepper) State() DebugState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return DebugState{
+		Phase:      s.phase,
+		Waiting:    s.waiting,
+		Continuous: s.continuous,
+	}
+}
+
+// InspectorAddr 获取 DevTools 端点的 host:port，显示窗口时为空
+func (s *Stepper) InspectorAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inspectorAddr
+}
+
+// setInspectorAddr 记录 DevTools 端点地址
+func (s *Stepper) setInspectorAddr(addr string) {
+	s.mu.Lock()
+	s.inspectorAddr = addr
+	s.mu.Unlock()
+}
+
+// SetStepper 启用调试模式：有图形界面时显示浏览器窗口，并在各阶段之间暂停
+func (b *BrowserExecutor) SetStepper(stepper *Stepper) {
+	b.stepper = stepper
+}
+
+// step 调试模式下在阶段开始前等待
+func (b *BrowserExecutor) step(ctx context.Context, phase string) error {
+	if b.stepper == nil {
+		return nil
+	}
+	b.sendProgress("debug_step", "调试: 即将执行「"+phase+"」", 0, 0)
+	return b.stepper.Wait(ctx, phase)
+}
+
+// hasDisplay 检查是否可以显示浏览器窗口
+func hasDisplay() bool {
+	if runtime.GOOS != "linux" {
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// inspectorAddr 获取调试时 DevTools 端点的 host:port，显示窗口时返回空
+func inspectorAddr(strategy AllocatorStrategy) string {
+	switch st := strategy.(type) {
+	case *remoteStrategy:
+		u, err := url.Parse(st.url)
+		if err != nil {
+			return ""
+		}
+		return u.Host
+	case *localStrategy:
+		if st.debugPort != 0 {
+			return net.JoinHostPort("127.0.0.1", strconv.Itoa(st.debugPort))
+		}
+	}
+	return ""
+}
+
+// freePort 获取本机一个空闲端口，供 DevTools 监听
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"mosoteach/internal/processor"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+)
+
+// FetchPage 实现 processor.PageFetcher：在浏览器中打开页面，解析渲染后的 HTML
+func (b *BrowserExecutor) FetchPage(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	wait := elementWaitTime
+	if pageURL == courseURL {
+		wait = pageLoadWaitTime
+	}
+
+	// 浏览器操作必须在标签页上下文中执行，调用方取消或超时时中止本次操作（不关闭标签页）
+	runCtx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	var html string
+	if err := chromedp.Run(runCtx,
+		chromedp.Navigate(pageURL),
+		chromedp.Sleep(wait),
+		chromedp.OuterHTML(`html`, &html, chromedp.ByQuery),
+	); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("打开页面失败: %w", err)
+	}
+	return goquery.NewDocumentFromReader(strings.NewReader(html))
+}
+
+// browserDiscoverer 通过浏览器获取题库
+type browserDiscoverer struct {
+	b *BrowserExecutor
+}
+
+func (d *browserDiscoverer) Name() string {
+	return "浏览器"
+}
+
+func (d *browserDiscoverer) Discover(ctx context.Context, opts processor.DiscoverOptions) ([]processor.QuizInfo, error) {
+	return processor.DiscoverQuizzes(ctx, d.b, d.b.cfg, opts, d.b.reportDiscovery)
+}
+
+// reportDiscovery 将题库发现进度转发到前端
+func (b *BrowserExecutor) reportDiscovery(message string, progress, total int) {
+	if total > 0 {
+		b.sendProgress("progress", message, progress, total)
+		return
+	}
+	b.logf("%s", message)
+}
+
+// NewDiscoverer 按配置选择题库发现方式，HTTP 方式依赖登录后保存的 Cookie
+func (b *BrowserExecutor) NewDiscoverer() processor.Discoverer {
+	browserD := &browserDiscoverer{b: b}
+
+	mode := b.cfg.GetDiscovery()
+	if mode == processor.DiscoveryBrowser {
+		return browserD
+	}
+
+	proc, err := processor.NewDataProcessor(b.cfg)
+	if err != nil {
+		b.logf("创建数据处理器失败，改用浏览器获取题库: %v", err)
+		return browserD
+	}
+	proc.SetProgressFunc(b.reportDiscovery)
+
+	if mode == processor.DiscoveryHTTP {
+		return proc
+	}
+	return processor.WithFallback(proc, browserD, b.reportDiscovery)
+}
+
+// DiscoverQuizzes 按配置的发现方式获取题库列表（需在登录之后调用）
+func (b *BrowserExecutor) DiscoverQuizzes(ctx context.Context, opts processor.DiscoverOptions) ([]processor.QuizInfo, error) {
+	discoverer := b.NewDiscoverer()
+	b.logDebug("题库发现方式: %s", discoverer.Name())
+	return discoverer.Discover(ctx, opts)
+}
+
+// FetchQuizzesByBrowser 通过浏览器获取题库列表
+func (b *BrowserExecutor) FetchQuizzesByBrowser() ([]processor.QuizInfo, error) {
+	return b.FetchQuizzesByBrowserWithContext(context.Background())
+}
+
+// FetchQuizzesByBrowserWithContext 通过浏览器获取题库列表（带context）
+func (b *BrowserExecutor) FetchQuizzesByBrowserWithContext(ctx context.Context) ([]processor.QuizInfo, error) {
+	return (&browserDiscoverer{b: b}).Discover(ctx, processor.DiscoverOptions{})
+}
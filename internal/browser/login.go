@@ -0,0 +1,140 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const loginPollInterval = 500 * time.Millisecond // 登录结果检测间隔
+
+// 登录失败类型
+var (
+	ErrBadCredentials       = errors.New("账号或密码错误")
+	ErrAccountLocked        = errors.New("账号已被锁定")
+	ErrVerificationRequired = errors.New("需要完成安全验证")
+	ErrNetwork              = errors.New("网络连接失败")
+	ErrUnknownPage          = errors.New("登录后页面状态未知")
+)
+
+// LoginError 登录失败详情
+type LoginError struct {
+	Kind     error  // 失败类型（上面的 Err* 之一）
+	Detail   string // 页面提示或底层错误
+	Snapshot string // 失败时保存的页面快照路径
+}
+
+func (e *LoginError) Error() string {
+	if e.Detail == "" {
+		return "登录失败: " + e.Kind.Error()
+	}
+	return fmt.Sprintf("登录失败: %s（%s）", e.Kind.Error(), e.Detail)
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Kind
+}
+
+// loginState 登录后页面状态
+type loginState struct {
+	URL       string `json:"url"`
+	Error     string `json:"error"`
+	Captcha   bool   `json:"captcha"`
+	LoginForm bool   `json:"loginForm"`
+	Authed    bool   `json:"authed"`
+}
+
+// jsLoginState 读取登录页的错误提示、验证码和已登录标记
+const jsLoginState = `
+	(function() {
+		function visibleText(sel) {
+			var els = document.querySelectorAll(sel);
+			for (var i = 0; i < els.length; i++) {
+				if (els[i].offsetParent !== null && els[i].innerText.trim()) {
+					return els[i].innerText.trim();
+				}
+			}
+			return '';
+		}
+		var errorMsg = visibleText('.error-msg, .err-msg, .error-tip, .login-error, .tips-error, .el-message--error, .el-form-item__error, .layui-layer-content');
+		return {
+			url: location.href,
+			error: errorMsg,
+			captcha: !!document.querySelector('#captcha, .captcha, .geetest_panel, .verify-box, iframe[src*="captcha"]'),
+			loginForm: !!document.querySelector('#account-name'),
+			authed: !!document.querySelector('li.class-item, a[href*="m=logout"], .user-avatar')
+		};
+	})()
+`
+
+// waitLoginResult 点击登录后轮询页面状态，直到成功、出现错误提示或超时
+func (b *BrowserExecutor) waitLoginResult() error {
+	deadline := time.Now().Add(loginWaitTime)
+	var state loginState
+
+	for time.Now().Before(deadline) {
+		if err := chromedp.Run(b.ctx, chromedp.Sleep(loginPollInterval)); err != nil {
+			return b.loginFailure(ErrNetwork, err.Error())
+		}
+
+		// 页面跳转过程中执行脚本可能失败，继续等待即可
+		if err := chromedp.Run(b.ctx, chromedp.Evaluate(jsLoginState, &state)); err != nil {
+			continue
+		}
+
+		if state.Authed || (!state.LoginForm && !strings.Contains(state.URL, "passport")) {
+			return nil
+		}
+		if state.Error != "" {
+			return b.loginFailure(classifyLoginMessage(state.Error), state.Error)
+		}
+		if state.Captcha {
+			return b.loginFailure(ErrVerificationRequired, "")
+		}
+	}
+
+	if strings.HasPrefix(state.URL, "chrome-error://") {
+		return b.loginFailure(ErrNetwork, state.URL)
+	}
+	return b.loginFailure(ErrUnknownPage, state.URL)
+}
+
+// classifyLoginMessage 根据登录页提示文字判断失败类型
+func classifyLoginMessage(msg string) error {
+	switch {
+	case strings.Contains(msg, "验证"):
+		return ErrVerificationRequired
+	case strings.Contains(msg, "锁定") || strings.Contains(msg, "冻结") || strings.Contains(msg, "频繁"):
+		return ErrAccountLocked
+	case strings.Contains(msg, "网络") || strings.Contains(msg, "超时"):
+		return ErrNetwork
+	case strings.Contains(msg, "密码") || strings.Contains(msg, "账号") || strings.Contains(msg, "错误"):
+		return ErrBadCredentials
+	default:
+		return ErrUnknownPage
+	}
+}
+
+// classifyRunError 判断浏览器操作错误是否为网络问题
+func classifyRunError(err error) error {
+	if strings.Contains(err.Error(), "net::ERR_") {
+		return ErrNetwork
+	}
+	return ErrUnknownPage
+}
+
+// loginFailure 保存失败现场并构造登录错误
+func (b *BrowserExecutor) loginFailure(kind error, detail string) error {
+	loginErr := &LoginError{Kind: kind, Detail: detail}
+
+	path, err := b.captureFailure("login", loginErr)
+	if err != nil {
+		b.logDebug("保存登录失败现场失败: %v", err)
+	} else {
+		loginErr.Snapshot = path
+	}
+	return loginErr
+}
@@ -0,0 +1,305 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+const maxQuizPages = 200 // 分页题库最多遍历的页数，防止翻页失效时死循环
+
+// 分页模式
+const (
+	paginationSingle = "single" // 所有题目在同一页
+	paginationNext   = "next"   // 通过“下一题/下一页”按钮翻页
+	paginationTabs   = "tabs"   // 按分组标签页切换
+)
+
+// quizPagination 题库分页信息
+type quizPagination struct {
+	Mode string `json:"mode"`
+	Tabs int    `json:"tabs"` // 标签页数量（仅 tabs 模式）
+}
+
+// paginationControls 页面上可见的分页控件
+type paginationControls struct {
+	Tabs        int `json:"tabs"`        // 分组标签页数量
+	NextButtons int `json:"nextButtons"` // “下一题/下一页”按钮数量
+}
+
+// jsDetectPagination 统计题库页面上可见的分页控件
+const jsDetectPagination = `
+	(function() {
+		function visible(el) { return el && el.offsetParent !== null; }
+		var tabs = Array.from(document.querySelectorAll('.el-tabs__item, .section-tab, .group-tab')).filter(visible);
+		var nexts = Array.from(document.querySelectorAll('button, a, .btn')).filter(function(el) {
+			var t = (el.innerText || '').trim();
+			return visible(el) && (t === '下一题' || t === '下一页');
+		});
+		return {tabs: tabs.length, nextButtons: nexts.length};
+	})()
+`
+
+// pagination 根据分页控件判断分页方式：多个标签页优先，其次是翻页按钮
+func (c paginationControls) pagination() quizPagination {
+	if c.Tabs > 1 {
+		return quizPagination{Mode: paginationTabs, Tabs: c.Tabs}
+	}
+	if c.NextButtons > 0 {
+		return quizPagination{Mode: paginationNext}
+	}
+	return quizPagination{Mode: paginationSingle}
+}
+
+// jsClickNavButton 点击指定文字的可用翻页按钮，返回是否点击成功
+const jsClickNavButton = `
+	(function(labels) {
+		var els = document.querySelectorAll('button, a, .btn');
+		for (var i = 0; i < els.length; i++) {
+			var el = els[i];
+			var t = (el.innerText || '').trim();
+			if (labels.indexOf(t) === -1 || el.offsetParent === null) continue;
+			if (el.disabled || el.classList.contains('is-disabled') || el.classList.contains('disabled')) return false;
+			el.click();
+			return true;
+		}
+		return false;
+	})(%s)
+`
+
+// jsPageIndex 读取分页器或答题卡中当前激活项的序号，页面没有分页器时返回 -1
+const jsPageIndex = `
+	(function() {
+		var active = document.querySelector('.el-pager li.active, .el-pager li.is-active, .pagination .active, .question-nav .active, .answer-card .active, .answer-card .current');
+		if (!active || !active.parentNode) return -1;
+		return Array.prototype.indexOf.call(active.parentNode.children, active);
+	})()
+`
+
+// jsClickTab 点击第 N 个分组标签页
+const jsClickTab = `
+	(function(idx) {
+		var tabs = Array.from(document.querySelectorAll('.el-tabs__item, .section-tab, .group-tab')).filter(function(el) {
+			return el.offsetParent !== null;
+		});
+		if (idx >= tabs.length) return false;
+		tabs[idx].click();
+		return true;
+	})(%d)
+`
+
+// detectPagination 检测当前题库页面的分页方式
+func (b *BrowserExecutor) detectPagination() quizPagination {
+	var controls paginationControls
+	if err := chromedp.Run(b.ctx, chromedp.Evaluate(jsDetectPagination, &controls)); err != nil {
+		b.logDebug("检测分页失败，按单页处理: %v", err)
+		return quizPagination{Mode: paginationSingle}
+	}
+	return controls.pagination()
+}
+
+// gotoPage 切换到第 page 页（从 0 开始），返回目标页是否存在
+// next 模式只支持从当前页前进一页
+func (b *BrowserExecutor) gotoPage(pagination quizPagination, page int) (bool, error) {
+	var ok bool
+	var js string
+	switch pagination.Mode {
+	case paginationTabs:
+		js = fmt.Sprintf(jsClickTab, page)
+	case paginationNext:
+		js = fmt.Sprintf(jsClickNavButton, `['下一题', '下一页']`)
+	default:
+		return page == 0, nil
+	}
+
+	if err := chromedp.Run(b.ctx,
+		chromedp.Evaluate(js, &ok),
+		chromedp.Sleep(shortWaitTime),
+	); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// pageIndex 获取 next 模式下分页器的当前页序号，无法判断时返回 -1
+func (b *BrowserExecutor) pageIndex(pagination quizPagination) int {
+	if pagination.Mode != paginationNext {
+		return -1
+	}
+	index := -1
+	if err := chromedp.Run(b.ctx, chromedp.Evaluate(jsPageIndex, &index)); err != nil {
+		return -1
+	}
+	return index
+}
+
+// gotoFirstPage 回到第一页
+func (b *BrowserExecutor) gotoFirstPage(pagination quizPagination) error {
+	switch pagination.Mode {
+	case paginationTabs:
+		_, err := b.gotoPage(pagination, 0)
+		return err
+	case paginationNext:
+		prevJS := fmt.Sprintf(jsClickNavButton, `['上一题', '上一页']`)
+		index := b.pageIndex(pagination)
+		signature, err := b.pageSignature()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < maxQuizPages; i++ {
+			var ok bool
+			if err := chromedp.Run(b.ctx,
+				chromedp.Evaluate(prevJS, &ok),
+				chromedp.Sleep(shortWaitTime),
+			); err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+
+			// 第一页的“上一题”按钮仍可点击时，页面不会变化
+			nextIndex := b.pageIndex(pagination)
+			nextSignature, err := b.pageSignature()
+			if err != nil {
+				return err
+			}
+			if samePage(index, nextIndex, signature, nextSignature) {
+				return nil
+			}
+			index, signature = nextIndex, nextSignature
+		}
+	}
+	return nil
+}
+
+// pageSignature 获取当前页题目的签名，用于判断翻页后页面是否变化
+func (b *BrowserExecutor) pageSignature() (string, error) {
+	var htmlContent string
+	if err := chromedp.Run(b.ctx, chromedp.OuterHTML(`html`, &htmlContent, chromedp.ByQuery)); err != nil {
+		return "", err
+	}
+	questions, err := b.parseQuestions(htmlContent, true)
+	if err != nil {
+		return "", err
+	}
+	return questionsSignature(questions), nil
+}
+
+// questionsSignature 由每道题的指纹拼接而成，题目相同的两页签名相同
+func questionsSignature(questions []Question) string {
+	keys := make([]string, len(questions))
+	for i, q := range questions {
+		keys[i] = questionKey(q)
+	}
+	return strings.Join(keys, ",")
+}
+
+// samePage 判断翻页前后是否为同一页：分页器序号可用时比较序号，否则比较题目签名
+func samePage(beforeIndex, afterIndex int, beforeSignature, afterSignature string) bool {
+	if beforeIndex >= 0 && afterIndex >= 0 {
+		return beforeIndex == afterIndex
+	}
+	return beforeSignature == afterSignature
+}
+
+// collectQuestionPages 遍历所有分页解析题目，结束后回到第一页
+func (b *BrowserExecutor) collectQuestionPages(pagination quizPagination, htmlContent string) ([][]Question, error) {
+	if pagination.Mode == paginationSingle {
+		questions, err := b.parseQuestions(htmlContent, false)
+		if err != nil {
+			return nil, err
+		}
+		return [][]Question{questions}, nil
+	}
+
+	b.logf("检测到分页题库（%s 模式），逐页解析题目...", pagination.Mode)
+
+	var pages [][]Question
+	index := b.pageIndex(pagination)
+	signature := ""
+	for page := 0; page < maxQuizPages; page++ {
+		if page > 0 {
+			more, err := b.gotoPage(pagination, page)
+			if err != nil {
+				return nil, fmt.Errorf("翻页失败: %w", err)
+			}
+			// 翻页按钮不可用或已不存在，说明已经是最后一页
+			if !more {
+				break
+			}
+		}
+
+		if err := chromedp.Run(b.ctx, chromedp.OuterHTML(`html`, &htmlContent, chromedp.ByQuery)); err != nil {
+			return nil, fmt.Errorf("获取第 %d 页内容失败: %w", page+1, err)
+		}
+		questions, err := b.parseQuestions(htmlContent, true)
+		if err != nil {
+			return nil, fmt.Errorf("解析第 %d 页失败: %w", page+1, err)
+		}
+
+		// next 模式下最后一页的翻页按钮仍可点击时，点击后分页器序号或题目不变，同样说明已经是最后一页
+		nextIndex, nextSignature := b.pageIndex(pagination), questionsSignature(questions)
+		if page > 0 && pagination.Mode == paginationNext && samePage(index, nextIndex, signature, nextSignature) {
+			break
+		}
+		index, signature = nextIndex, nextSignature
+
+		b.logDebug("第 %d 页: %d 题", page+1, len(questions))
+		pages = append(pages, questions)
+	}
+
+	if err := b.gotoFirstPage(pagination); err != nil {
+		return nil, fmt.Errorf("返回第一页失败: %w", err)
+	}
+	b.logf("共 %d 页", len(pages))
+	return pages, nil
+}
+
+// fillQuestionPages 逐页填写答案，answers 与所有页的题目按顺序一一对应
+func (b *BrowserExecutor) fillQuestionPages(pagination quizPagination, pages [][]Question, answers []string) (int, error) {
+	visibleOnly := pagination.Mode != paginationSingle
+	filled := 0
+	for page, pageAnswers := range splitAnswers(pages, answers) {
+		if page > 0 {
+			ok, err := b.gotoPage(pagination, page)
+			if err != nil {
+				return filled, fmt.Errorf("翻页失败: %w", err)
+			}
+			if !ok {
+				return filled, fmt.Errorf("无法切换到第 %d 页", page+1)
+			}
+		}
+
+		count, err := b.batchSubmitAnswers(pages[page], pageAnswers, visibleOnly)
+		if err != nil {
+			return filled, fmt.Errorf("填写第 %d 页失败: %w", page+1, err)
+		}
+		filled += count
+	}
+	return filled, nil
+}
+
+// splitAnswers 按每页的题目数量拆分答案，答案不足时缺少的题目为空
+func splitAnswers(pages [][]Question, answers []string) [][]string {
+	result := make([][]string, len(pages))
+	offset := 0
+	for page, questions := range pages {
+		result[page] = make([]string, len(questions))
+		if offset < len(answers) {
+			copy(result[page], answers[offset:])
+		}
+		offset += len(questions)
+	}
+	return result
+}
+
+// flattenPages 合并所有页的题目
+func flattenPages(pages [][]Question) []Question {
+	var all []Question
+	for _, questions := range pages {
+		all = append(all, questions...)
+	}
+	return all
+}
@@ -0,0 +1,81 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPaginationControls(t *testing.T) {
+	tests := []struct {
+		name     string
+		controls paginationControls
+		want     quizPagination
+	}{
+		{name: "分组标签页", controls: paginationControls{Tabs: 3, NextButtons: 1}, want: quizPagination{Mode: paginationTabs, Tabs: 3}},
+		{name: "下一题按钮", controls: paginationControls{NextButtons: 1}, want: quizPagination{Mode: paginationNext}},
+		{name: "只有一个标签页时按翻页按钮判断", controls: paginationControls{Tabs: 1, NextButtons: 2}, want: quizPagination{Mode: paginationNext}},
+		{name: "单页", controls: paginationControls{Tabs: 1}, want: quizPagination{Mode: paginationSingle}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.controls.pagination(); got != tt.want {
+				t.Errorf("pagination() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSamePage(t *testing.T) {
+	first := questionsSignature([]Question{{Type: QuestionTypeSingle, Content: "第一题"}})
+	second := questionsSignature([]Question{{Type: QuestionTypeSingle, Content: "第二题"}})
+
+	tests := []struct {
+		name                            string
+		beforeIndex, afterIndex         int
+		beforeSignature, afterSignature string
+		want                            bool
+	}{
+		{name: "分页器前进", beforeIndex: 0, afterIndex: 1, beforeSignature: first, afterSignature: second, want: false},
+		{name: "最后一页按钮仍可点击，分页器不变", beforeIndex: 4, afterIndex: 4, beforeSignature: first, afterSignature: first, want: true},
+		{name: "分页器序号优先于题目", beforeIndex: 0, afterIndex: 1, beforeSignature: first, afterSignature: first, want: false},
+		{name: "没有分页器时题目变化", beforeIndex: -1, afterIndex: -1, beforeSignature: first, afterSignature: second, want: false},
+		{name: "最后一页按钮仍可点击，没有分页器时题目不变", beforeIndex: -1, afterIndex: -1, beforeSignature: first, afterSignature: first, want: true},
+		{name: "分页器消失时比较题目", beforeIndex: 2, afterIndex: -1, beforeSignature: first, afterSignature: first, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := samePage(tt.beforeIndex, tt.afterIndex, tt.beforeSignature, tt.afterSignature); got != tt.want {
+				t.Errorf("samePage = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAnswers(t *testing.T) {
+	pages := [][]Question{
+		{{Content: "1"}, {Content: "2"}},
+		{},
+		{{Content: "3"}},
+	}
+
+	tests := []struct {
+		name    string
+		answers []string
+		want    [][]string
+	}{
+		{name: "按页拆分", answers: []string{"A", "B", "C"}, want: [][]string{{"A", "B"}, {}, {"C"}}},
+		{name: "答案不足", answers: []string{"A"}, want: [][]string{{"A", ""}, {}, {""}}},
+		{name: "没有答案", answers: nil, want: [][]string{{"", ""}, {}, {""}}},
+		{name: "多余的答案忽略", answers: []string{"A", "B", "C", "D"}, want: [][]string{{"A", "B"}, {}, {"C"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitAnswers(pages, tt.answers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitAnswers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mosoteach/internal/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	sessionIdleTimeout  = 10 * time.Minute // 浏览器空闲多久后自动关闭
+	sessionWaitTimeout  = 10 * time.Second // 等待其他任务归还标签页的最长时间
+	sessionPingTimeout  = 5 * time.Second  // 存活检查超时
+	sessionProbeTimeout = 10 * time.Second // 登录状态检查超时
+	siteOrigin          = "https://www.mosoteach.cn"
+)
+
+// sessionProbeJS 在当前页面内请求课程列表，未登录时会被重定向到登录页
+const sessionProbeJS = `
+	(async function() {
+		try {
+			var resp = await fetch('/web/index.php?c=clazzcourse&m=index', {credentials: 'include'});
+			return resp.ok && resp.url.indexOf('passport') === -1;
+		} catch (e) {
+			return false;
+		}
+	})()
+`
+
+// ErrSessionBusy 浏览器标签页正被其他任务使用
+var ErrSessionBusy = errors.New("浏览器正被其他任务使用，请稍后再试")
+
+// SessionManager 长期存活的浏览器会话，在多次 API 调用之间复用同一个已登录的标签页
+type SessionManager struct {
+	cfg         *config.Config
+	idleTimeout time.Duration
+
+	inUse chan struct{} // 同一时间只允许一个调用者使用标签页（容量为 1 的信号量）
+
+	mu          sync.Mutex // 保护以下字段
+	allocCtx    context.Context
+	allocCancel context.CancelFunc
+	tabCtx      context.Context
+	tabCancel   context.CancelFunc
+	loggedIn    bool
+	idleTimer   *time.Timer
+}
+
+// NewSessionManager 创建浏览器会话管理器
+func NewSessionManager(cfg *config.Config) *SessionManager {
+	return &SessionManager{
+		cfg:         cfg,
+		idleTimeout: sessionIdleTimeout,
+		inUse:       make(chan struct{}, 1),
+	}
+}
+
+// Acquire 获取浏览器标签页的独占使用权，必要时启动或重启浏览器
+// 标签页被占用时最多等待 sessionWaitTimeout，超时或 ctx 取消时返回 ErrSessionBusy
+// 调用者使用完毕后必须调用 Release
+func (s *SessionManager) Acquire(ctx context.Context) (context.Context, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, sessionWaitTimeout)
+	defer cancel()
+	select {
+	case s.inUse <- struct{}{}:
+	case <-waitCtx.Done():
+		return nil, ErrSessionBusy
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+		s.idleTimer = nil
+	}
+
+	// 浏览器崩溃或远程连接断开时重新启动/连接
+	if s.tabCtx != nil && !s.aliveLocked() {
+		fmt.Println("[DEBUG] 浏览器已失去响应，正在重新连接...")
+		s.shutdownLocked()
+	}
+
+	if s.tabCtx == nil {
+		if err := s.launchLocked(); err != nil {
+			<-s.inUse
+			return nil, err
+		}
+	}
+
+	return s.tabCtx, nil
+}
+
+// Release 归还标签页使用权，并开始计算空闲时间
+func (s *SessionManager) Release() {
+	s.mu.Lock()
+	if s.tabCtx != nil && s.idleTimeout > 0 {
+		s.idleTimer = time.AfterFunc(s.idleTimeout, s.closeIfIdle)
+	}
+	s.mu.Unlock()
+
+	<-s.inUse
+}
+
+// IsLoggedIn 检查当前会话是否仍处于登录状态（需在 Acquire 之后调用）
+func (s *SessionManager) IsLoggedIn() bool {
+	s.mu.Lock()
+	tabCtx, loggedIn := s.tabCtx, s.loggedIn
+	s.mu.Unlock()
+
+	if tabCtx == nil || !loggedIn {
+		return false
+	}
+
+	probeCtx, cancel := context.WithTimeout(tabCtx, sessionProbeTimeout)
+	defer cancel()
+
+	var location string
+	if err := chromedp.Run(probeCtx, chromedp.Location(&location)); err != nil {
+		return false
+	}
+	// fetch 需要在云班课域名下执行才能带上 Cookie
+	if !strings.HasPrefix(location, siteOrigin) {
+		return false
+	}
+
+	var ok bool
+	err := chromedp.Run(probeCtx,
+		chromedp.Evaluate(sessionProbeJS, &ok, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}),
+	)
+	if err != nil || !ok {
+		s.SetLoggedIn(false)
+		return false
+	}
+	return true
+}
+
+// SetLoggedIn 记录会话登录状态
+func (s *SessionManager) SetLoggedIn(loggedIn bool) {
+	s.mu.Lock()
+	s.loggedIn = loggedIn
+	s.mu.Unlock()
+}
+
+// Logout 账号变化后清除标签页中的Cookie，下次使用时重新登录
+func (s *SessionManager) Logout() error {
+	s.mu.Lock()
+	tabCtx := s.tabCtx
+	s.loggedIn = false
+	s.mu.Unlock()
+
+	if tabCtx == nil || tabCtx.Err() != nil {
+		return nil
+	}
+	clearCtx, cancel := context.WithTimeout(tabCtx, sessionPingTimeout)
+	defer cancel()
+	return chromedp.Run(clearCtx, network.ClearBrowserCookies())
+}
+
+// Close 关闭浏览器
+func (s *SessionManager) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+		s.idleTimer = nil
+	}
+	s.shutdownLocked()
+}
+
+// closeIfIdle 空闲超时后关闭浏览器（正在使用时跳过）
+func (s *SessionManager) closeIfIdle() {
+	select {
+	case s.inUse <- struct{}{}:
+	default:
+		return
+	}
+	defer func() { <-s.inUse }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tabCtx == nil {
+		return
+	}
+	fmt.Println("[DEBUG] 浏览器空闲超时，正在关闭...")
+	s.idleTimer = nil
+	s.shutdownLocked()
+}
+
+// launchLocked 按配置的分配策略启动或连接浏览器并打开标签页
+func (s *SessionManager) launchLocked() error {
+	strategy := newAllocatorStrategy(s.cfg, false)
+	fmt.Printf("[DEBUG] 使用%s\n", strategy.Name())
+
+	allocCtx, allocCancel, tabCtx, tabCancel, err := allocate(strategy)
+	if err != nil {
+		return err
+	}
+	s.allocCtx, s.allocCancel = allocCtx, allocCancel
+	s.tabCtx, s.tabCancel = tabCtx, tabCancel
+	s.loggedIn = false
+	return nil
+}
+
+// aliveLocked 检查浏览器是否仍可响应
+func (s *SessionManager) aliveLocked() bool {
+	if s.tabCtx.Err() != nil {
+		return false
+	}
+	pingCtx, cancel := context.WithTimeout(s.tabCtx, sessionPingTimeout)
+	defer cancel()
+
+	var result int
+	return chromedp.Run(pingCtx, chromedp.Evaluate(`1`, &result)) == nil
+}
+
+// shutdownLocked 关闭标签页并退出浏览器（远程浏览器只断开连接）
+func (s *SessionManager) shutdownLocked() {
+	if s.tabCancel != nil {
+		s.tabCancel()
+		s.tabCancel = nil
+	}
+	if s.allocCancel != nil {
+		s.allocCancel()
+		s.allocCancel = nil
+	}
+	s.tabCtx = nil
+	s.allocCtx = nil
+	s.loggedIn = false
+}
@@ -0,0 +1,181 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	submitReserve      = 30 * time.Second // 截止前预留给填写和提交的时间
+	fastModeThreshold  = 5 * time.Minute  // 剩余时间低于该值时直接进入快速模式
+	estimatedBatchTime = 40 * time.Second // 预估每批 AI 请求耗时，用于判断时间是否够用
+	fastBatchSize      = 50               // 快速模式下每批题目数量
+)
+
+// jsReadCountdown 读取测验页面上的剩余时间文字，没有倒计时返回空字符串
+const jsReadCountdown = `
+	(function() {
+		function visible(el) { return el && el.offsetParent !== null; }
+		var els = document.querySelectorAll('.count-down, .countdown, .count-time, .time-left, .left-time, .remain-time, .rest-time, .timer, .time-count');
+		for (var i = 0; i < els.length; i++) {
+			var t = (els[i].innerText || '').trim();
+			if (visible(els[i]) && /\d/.test(t)) return t;
+		}
+		// 按提示文字查找
+		var nodes = document.querySelectorAll('div, span, p');
+		for (var j = 0; j < nodes.length; j++) {
+			var el = nodes[j];
+			if (el.children.length > 3 || !visible(el)) continue;
+			var text = (el.innerText || '').trim();
+			if (text.length < 40 && /(剩余时间|倒计时|剩余)/.test(text) && /\d/.test(text)) return text;
+		}
+		return '';
+	})()
+`
+
+var (
+	clockPattern   = regexp.MustCompile(`(\d{1,3}):(\d{2})(?::(\d{2}))?`)
+	chineseHours   = regexp.MustCompile(`(\d+)\s*(?:小时|时)`)
+	chineseMinutes = regexp.MustCompile(`(\d+)\s*分`)
+	chineseSeconds = regexp.MustCompile(`(\d+)\s*秒`)
+)
+
+// parseCountdown 解析倒计时文字，支持 "01:23:45"、"23:45" 和 "1小时2分3秒" 等格式
+func parseCountdown(text string) (time.Duration, bool) {
+	if m := clockPattern.FindStringSubmatch(text); m != nil {
+		a, _ := strconv.Atoi(m[1])
+		b, _ := strconv.Atoi(m[2])
+		if m[3] == "" {
+			return time.Duration(a)*time.Minute + time.Duration(b)*time.Second, true
+		}
+		c, _ := strconv.Atoi(m[3])
+		return time.Duration(a)*time.Hour + time.Duration(b)*time.Minute + time.Duration(c)*time.Second, true
+	}
+
+	var total time.Duration
+	found := false
+	for _, unit := range []struct {
+		pattern *regexp.Regexp
+		scale   time.Duration
+	}{
+		{chineseHours, time.Hour},
+		{chineseMinutes, time.Minute},
+		{chineseSeconds, time.Second},
+	} {
+		if m := unit.pattern.FindStringSubmatch(text); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			total += time.Duration(n) * unit.scale
+			found = true
+		}
+	}
+	return total, found
+}
+
+// readTimeLimit 读取当前测验的剩余时间并记录截止时间，没有时间限制时清空
+func (b *BrowserExecutor) readTimeLimit(quizName string) {
+	b.timerMu.Lock()
+	b.deadline = time.Time{}
+	b.fastMode = false
+	b.timerMu.Unlock()
+
+	var text string
+	if err := chromedp.Run(b.ctx, chromedp.Evaluate(jsReadCountdown, &text)); err != nil {
+		b.logDebug("读取倒计时失败: %v", err)
+		return
+	}
+	remaining, ok := parseCountdown(text)
+	if !ok || remaining <= 0 {
+		return
+	}
+
+	b.timerMu.Lock()
+	b.deadline = time.Now().Add(remaining)
+	b.timerMu.Unlock()
+	b.logf("【%s】限时测验，剩余时间 %s", quizName, formatRemaining(remaining))
+}
+
+// TimeLeft 获取当前测验的剩余时间，没有时间限制时 ok 为 false
+func (b *BrowserExecutor) TimeLeft() (remaining time.Duration, fast bool, ok bool) {
+	b.timerMu.Lock()
+	defer b.timerMu.Unlock()
+	if b.deadline.IsZero() {
+		return 0, false, false
+	}
+	return time.Until(b.deadline), b.fastMode, true
+}
+
+// answerDeadline 作答阶段必须结束的时间：测验截止和浏览器总超时中较早者，再扣除提交预留时间
+func (b *BrowserExecutor) answerDeadline() (time.Time, bool) {
+	b.timerMu.Lock()
+	deadline := b.deadline
+	b.timerMu.Unlock()
+
+	if sessionDeadline, ok := b.ctx.Deadline(); ok && (deadline.IsZero() || sessionDeadline.Before(deadline)) {
+		deadline = sessionDeadline
+	}
+	if deadline.IsZero() {
+		return time.Time{}, false
+	}
+	return deadline.Add(-submitReserve), true
+}
+
+// withAnswerDeadline 为作答阶段设置截止时间，时间用完后停止请求答案并提交已有答案
+func (b *BrowserExecutor) withAnswerDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := b.answerDeadline(); ok {
+		return context.WithDeadline(ctx, deadline)
+	}
+	return context.WithCancel(ctx)
+}
+
+// checkTimeBudget 根据剩余时间和待答题数判断是否需要切换到快速模式，返回当前是否为快速模式
+func (b *BrowserExecutor) checkTimeBudget(pending int) bool {
+	b.timerMu.Lock()
+	fast := b.fastMode
+	b.timerMu.Unlock()
+	if fast {
+		return true
+	}
+
+	deadline, ok := b.answerDeadline()
+	if !ok {
+		return false
+	}
+
+	remaining := time.Until(deadline)
+	batches := (pending + batchSize - 1) / batchSize
+	needed := time.Duration(batches) * (estimatedBatchTime + time.Second)
+	if remaining >= fastModeThreshold && remaining >= needed {
+		return false
+	}
+
+	b.timerMu.Lock()
+	b.fastMode = true
+	b.timerMu.Unlock()
+
+	msg := fmt.Sprintf("剩余时间 %s 不足（剩余 %d 题），切换到快速模式：加大批次、优先使用题库缓存", formatRemaining(remaining), pending)
+	if name := b.modelManager.FastestModelName(); name != "" {
+		msg += "、只使用最快的模型 " + name
+	}
+	b.logf("%s", msg)
+	return true
+}
+
+// formatRemaining 格式化剩余时间
+func formatRemaining(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d % time.Hour / time.Minute)
+	s := int(d % time.Minute / time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
@@ -0,0 +1,50 @@
+package browser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCountdown(t *testing.T) {
+	tests := []struct {
+		name string
+		text string // 页面上倒计时元素的文字
+		want time.Duration
+		ok   bool
+	}{
+		{name: "时分秒", text: "剩余时间 01:23:45", want: time.Hour + 23*time.Minute + 45*time.Second, ok: true},
+		{name: "分秒", text: "23:45", want: 23*time.Minute + 45*time.Second, ok: true},
+		{name: "超过一小时的分钟数", text: "倒计时 125:00", want: 125 * time.Minute, ok: true},
+		{name: "中文时分秒", text: "剩余 1小时2分3秒", want: time.Hour + 2*time.Minute + 3*time.Second, ok: true},
+		{name: "只有分钟", text: "剩余 10 分钟", want: 10 * time.Minute, ok: true},
+		{name: "只有秒", text: "还剩 30秒", want: 30 * time.Second, ok: true},
+		{name: "时间已用完", text: "00:00", want: 0, ok: true},
+		{name: "没有倒计时", text: "", ok: false},
+		{name: "没有时间的文字", text: "不限时", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCountdown(tt.text)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseCountdown(%q) = %v, %v, want %v, %v", tt.text, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFormatRemaining(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 90 * time.Second, want: "01:30"},
+		{d: time.Hour + 2*time.Minute + 3*time.Second, want: "1:02:03"},
+		{d: -time.Second, want: "00:00"},
+	}
+	for _, tt := range tests {
+		if got := formatRemaining(tt.d); got != tt.want {
+			t.Errorf("formatRemaining(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
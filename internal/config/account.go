@@ -0,0 +1,345 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"mosoteach/internal/state"
+)
+
+// DefaultAccount 默认账号的ID，对应配置文件顶层的 user_data、courses 和 preferred_models
+const DefaultAccount = "default"
+
+// defaultAccountName 默认账号的显示名称
+const defaultAccountName = "默认账号"
+
+// ErrAccountNotFound 账号不存在
+var ErrAccountNotFound = errors.New("账号不存在")
+
+// accountIDPattern 账号ID只能包含字母、数字、下划线和连字符（用于状态文件名）
+var accountIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Account 账号配置，除默认账号外的账号保存在配置文件的 accounts 中
+// 每个账号有独立的登录信息、课程设置、运行状态（state-<ID>.json）和模型偏好
+type Account struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name,omitempty"`
+	UserData UserData        `json:"user_data"`
+	Courses  []CourseSetting `json:"courses,omitempty"`
+	Models   []string        `json:"models,omitempty"` // 偏好的模型名称（按顺序使用），为空时使用所有已启用的模型
+}
+
+// cloneAccounts 复制账号列表，避免修改共享的切片
+func cloneAccounts(accounts []Account) []Account {
+	result := slices.Clone(accounts)
+	for i := range result {
+		result[i].Courses = slices.Clone(result[i].Courses)
+		result[i].Models = slices.Clone(result[i].Models)
+	}
+	return result
+}
+
+// validateAccountID 检查账号ID是否有效
+func validateAccountID(id string) error {
+	if !accountIDPattern.MatchString(id) {
+		return fmt.Errorf("账号ID只能包含字母、数字、下划线和连字符，最长 32 个字符: %q", id)
+	}
+	return nil
+}
+
+// top 全局配置（账号视图返回所属的配置，模型、浏览器、Web 密码等设置由所有账号共享）
+func (c *Config) top() *Config {
+	if c.root != nil {
+		return c.root
+	}
+	return c
+}
+
+// AccountID 配置对应的账号ID
+func (c *Config) AccountID() string {
+	if c.root == nil {
+		return DefaultAccount
+	}
+	return c.accountID
+}
+
+// Account 获取账号的配置视图，为空或 default 时返回默认账号（即配置本身）
+// 视图有独立的账号、课程、模型偏好和状态存储，其余设置读写全局配置；同一账号总是返回同一个视图
+func (c *Config) Account(id string) (*Config, error) {
+	c = c.top()
+	if id == "" || id == DefaultAccount {
+		return c, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, entry := range c.Accounts {
+		if entry.ID == id {
+			return c.openAccount(entry)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+}
+
+// openAccount 获取或创建账号视图并打开其状态存储（调用方持有锁）
+func (c *Config) openAccount(entry Account) (*Config, error) {
+	c.viewMu.Lock()
+	defer c.viewMu.Unlock()
+
+	if view, ok := c.views[entry.ID]; ok {
+		return view, nil
+	}
+	if c.box == nil {
+		return nil, errors.New("请先加载配置")
+	}
+
+	view := &Config{
+		root:      c,
+		accountID: entry.ID,
+		StatePath: c.accountStatePath(entry.ID),
+	}
+	view.setAccount(entry)
+	store := state.NewFileStore(view.StatePath)
+	if err := store.SetCipher(c.box); err != nil {
+		return nil, err
+	}
+	if err := store.Open(); err != nil {
+		return nil, fmt.Errorf("打开账号 %s 的状态文件失败: %w", entry.ID, err)
+	}
+	view.state = store
+
+	if c.views == nil {
+		c.views = make(map[string]*Config)
+	}
+	c.views[entry.ID] = view
+	return view, nil
+}
+
+// accountStatePath 账号的状态文件路径，和默认账号的状态文件放在同一目录
+func (c *Config) accountStatePath(id string) string {
+	return filepath.Join(filepath.Dir(c.StatePath), "state-"+id+".json")
+}
+
+// importMoved 将升级配置文件时移出的运行状态导入对应账号的状态文件（调用方持有锁）
+func (c *Config) importMoved(moved movedState) error {
+	for id, legacy := range moved {
+		store, err := c.accountStore(id)
+		if err != nil {
+			return err
+		}
+		// 旧版配置中的登录会话可能已加密，无法解密时放弃（重新登录即可）
+		if legacy.Cookie, err = c.box.decrypt(legacy.Cookie); err != nil {
+			slog.Warn("无法解密旧版配置中的登录会话，需要重新登录", "account", id, "error", err)
+			legacy.Cookie = ""
+		}
+		if err := store.Import(*legacy); err != nil {
+			return fmt.Errorf("导入账号 %s 的运行状态失败: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// accountStore 账号的状态存储：已打开视图的直接使用，否则单独打开状态文件
+func (c *Config) accountStore(id string) (state.Store, error) {
+	if id == DefaultAccount {
+		return c.state, nil
+	}
+	if err := validateAccountID(id); err != nil {
+		return nil, err
+	}
+
+	c.viewMu.Lock()
+	view := c.views[id]
+	c.viewMu.Unlock()
+	if view != nil {
+		return view.state, nil
+	}
+
+	store := state.NewFileStore(c.accountStatePath(id))
+	if err := store.SetCipher(c.box); err != nil {
+		return nil, err
+	}
+	if err := store.Open(); err != nil {
+		return nil, fmt.Errorf("打开账号 %s 的状态文件失败: %w", id, err)
+	}
+	return store, nil
+}
+
+// setAccount 替换视图中的账号配置（调用方持有视图的锁或视图尚未共享）
+func (c *Config) setAccount(entry Account) {
+	c.accountName = entry.Name
+	c.UserData = entry.UserData
+	c.Courses = slices.Clone(entry.Courses)
+	c.PreferredModels = slices.Clone(entry.Models)
+}
+
+// account 视图当前的账号配置（调用方持有锁）
+func (c *Config) account() Account {
+	return Account{
+		ID:       c.accountID,
+		Name:     c.accountName,
+		UserData: c.UserData,
+		Courses:  slices.Clone(c.Courses),
+		Models:   slices.Clone(c.PreferredModels),
+	}
+}
+
+// saveAccount 将账号视图的修改写回配置文件
+func (c *Config) saveAccount(entry Account) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.Accounts {
+		if c.Accounts[i].ID == entry.ID {
+			c.Accounts = cloneAccounts(c.Accounts)
+			c.Accounts[i] = entry
+			return c.saveInternal()
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrAccountNotFound, entry.ID)
+}
+
+// refreshAccounts 配置重新加载后更新已打开的账号视图，移除已删除账号的视图
+func (c *Config) refreshAccounts() {
+	c.mu.RLock()
+	entries := cloneAccounts(c.Accounts)
+	c.mu.RUnlock()
+
+	c.viewMu.Lock()
+	views := make(map[string]*Config, len(c.views))
+	for id, view := range c.views {
+		if !slices.ContainsFunc(entries, func(a Account) bool { return a.ID == id }) {
+			delete(c.views, id)
+			continue
+		}
+		views[id] = view
+	}
+	c.viewMu.Unlock()
+
+	for _, entry := range entries {
+		if view, ok := views[entry.ID]; ok {
+			view.mu.Lock()
+			view.setAccount(entry)
+			view.mu.Unlock()
+		}
+	}
+}
+
+// ListAccounts 列出所有账号，默认账号在最前
+func (c *Config) ListAccounts() []Account {
+	c = c.top()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := []Account{{
+		ID:       DefaultAccount,
+		Name:     defaultAccountName,
+		UserData: c.UserData,
+		Courses:  slices.Clone(c.Courses),
+		Models:   slices.Clone(c.PreferredModels),
+	}}
+	return append(result, cloneAccounts(c.Accounts)...)
+}
+
+// SaveAccount 添加或更新账号：名称和模型偏好整体替换，账号和密码为空时保持不变
+// 返回登录信息是否有变化（需要重新登录）
+func (c *Config) SaveAccount(entry Account) (bool, error) {
+	c = c.top()
+	if entry.ID == "" {
+		entry.ID = DefaultAccount
+	}
+	if errs := validateAccount("", entry); len(errs) > 0 {
+		return false, ValidationErrors(errs)
+	}
+
+	view, err := c.Account(entry.ID)
+	if errors.Is(err, ErrAccountNotFound) {
+		return true, c.addAccount(entry)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	view.mu.Lock()
+	defer view.mu.Unlock()
+
+	if view.root != nil {
+		view.accountName = entry.Name
+	}
+	view.PreferredModels = slices.Clone(entry.Models)
+	changed := (entry.UserData.UserName != "" && entry.UserData.UserName != view.UserData.UserName) ||
+		(entry.UserData.Password != "" && entry.UserData.Password != view.UserData.GetPassword())
+	if entry.UserData.UserName != "" {
+		view.UserData.UserName = entry.UserData.UserName
+	}
+	if entry.UserData.Password != "" {
+		view.UserData.SetPassword(entry.UserData.Password)
+	}
+	return changed, view.saveInternal()
+}
+
+// addAccount 添加新账号
+func (c *Config) addAccount(entry Account) error {
+	if entry.UserData.UserName == "" {
+		return ValidationErrors{{Field: "user_data.user_name", Message: "新账号的用户名不能为空"}}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if slices.ContainsFunc(c.Accounts, func(a Account) bool { return a.ID == entry.ID }) {
+		return fmt.Errorf("账号 %s 已存在", entry.ID)
+	}
+	c.Accounts = append(cloneAccounts(c.Accounts), Account{
+		ID:       entry.ID,
+		Name:     entry.Name,
+		UserData: UserData{UserName: entry.UserData.UserName, Password: entry.UserData.Password},
+		Models:   slices.Clone(entry.Models),
+	})
+	return c.saveInternal()
+}
+
+// RemoveAccount 删除账号及其状态文件（登录会话、题库缓存和完成记录），默认账号不能删除
+func (c *Config) RemoveAccount(id string) error {
+	c = c.top()
+	if id == "" || id == DefaultAccount {
+		return errors.New("默认账号不能删除")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := slices.IndexFunc(c.Accounts, func(a Account) bool { return a.ID == id })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+	}
+	c.Accounts = slices.Delete(cloneAccounts(c.Accounts), i, i+1)
+	if err := c.saveInternal(); err != nil {
+		return err
+	}
+
+	c.viewMu.Lock()
+	delete(c.views, id)
+	c.viewMu.Unlock()
+
+	return state.Remove(c.accountStatePath(id))
+}
+
+// preferModels 按账号的模型偏好筛选并排序已启用的模型，没有偏好时原样返回
+func preferModels(enabled []ModelConfig, preferred []string) []ModelConfig {
+	if len(preferred) == 0 {
+		return enabled
+	}
+	var result []ModelConfig
+	for _, name := range preferred {
+		i := slices.IndexFunc(enabled, func(m ModelConfig) bool { return m.Name == name })
+		if i >= 0 {
+			result = append(result, enabled[i])
+		}
+	}
+	return result
+}
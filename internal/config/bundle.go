@@ -0,0 +1,520 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 配置包用于在不同机器之间迁移配置，只包含配置不包含运行状态（登录会话、题库缓存、完成记录）
+const (
+	bundleFormat  = "mosoteach-config"
+	bundleVersion = 1
+	bundleCheck   = "mosoteach" // 口令加密时用于验证口令的已知明文
+)
+
+// 配置包中可以选择的内容
+const (
+	SectionModels   = "models"   // 模型列表
+	SectionSettings = "settings" // 提交延迟、浏览器、限速、Web 访问密码等设置
+	SectionAccounts = "accounts" // 账号、课程设置和模型偏好
+)
+
+// BundleSections 所有可以导出的内容
+var BundleSections = []string{SectionModels, SectionSettings, SectionAccounts}
+
+// 配置包中敏感字段（API Key、额外请求头、代理密码、账号密码、Web 访问密码）的处理方式
+const (
+	SecretsRedact  = "redact"  // 清空（请求头的值和代理密码显示为 ******，默认），导入时保留已有的值
+	SecretsPlain   = "plain"   // 明文
+	SecretsEncrypt = "encrypt" // 使用口令加密
+)
+
+// redactedSecret 差异中敏感字段的显示值
+const redactedSecret = "******"
+
+// Bundle 配置包
+type Bundle struct {
+	Format     string          `json:"format"`
+	Version    int             `json:"version"`
+	ExportedAt int64           `json:"exported_at"`
+	Sections   []string        `json:"sections"`
+	Secrets    string          `json:"secrets"`
+	KeySalt    string          `json:"key_salt,omitempty"` // 口令派生密钥使用的盐
+	Check      string          `json:"check,omitempty"`    // 加密的已知明文，用于验证口令
+	Models     []ModelConfig   `json:"models,omitempty"`
+	Settings   *BundleSettings `json:"settings,omitempty"`
+	Accounts   []Account       `json:"accounts,omitempty"` // 包括默认账号（ID 为 default）
+}
+
+// BundleSettings 配置包中的设置
+type BundleSettings struct {
+	Debug       bool       `json:"debug"`
+	SubmitDelay int        `json:"submit_delay"`
+	Listen      string     `json:"listen"`
+	WebPassword string     `json:"web_password"`
+	ChromePath  string     `json:"chrome_path"`
+	BrowserURL  string     `json:"browser_url"`
+	Discovery   string     `json:"discovery"`
+	RateLimit   *RateLimit `json:"rate_limit,omitempty"`
+}
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	Sections   []string // 为空时导出全部内容
+	Secrets    string   // 为空时清空敏感字段
+	Passphrase string   // Secrets 为 encrypt 时使用的口令
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Sections   []string // 为空时导入配置包中的全部内容
+	Passphrase string   // 配置包使用口令加密时需要
+	DryRun     bool     // 只返回差异，不修改配置
+}
+
+// Change 导入前后配置的差异
+type Change struct {
+	Field  string `json:"field"`
+	Action string `json:"action"` // add / update / remove
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+
+	// 该项被环境变量（env）或命令行参数（flag）覆盖：导入的值写入配置文件，本次运行中不生效
+	Overridden string `json:"overridden,omitempty"`
+}
+
+// Encrypted 配置包中的敏感字段是否使用口令加密
+func (b Bundle) Encrypted() bool {
+	return b.Secrets == SecretsEncrypt
+}
+
+// selectSections 检查并返回要处理的内容，为空时返回 available
+func selectSections(requested, available []string) ([]string, error) {
+	if len(requested) == 0 {
+		return slices.Clone(available), nil
+	}
+	var result []string
+	for _, section := range requested {
+		section = strings.TrimSpace(section)
+		switch {
+		case section == "prompts":
+			return nil, errors.New("提示词内置在程序中，不支持导入导出")
+		case !slices.Contains(BundleSections, section):
+			return nil, fmt.Errorf("未知的内容: %s（可选 %s）", section, strings.Join(BundleSections, "、"))
+		case !slices.Contains(available, section):
+			return nil, fmt.Errorf("配置包中没有 %s", section)
+		}
+		if !slices.Contains(result, section) {
+			result = append(result, section)
+		}
+	}
+	return result, nil
+}
+
+// bundleSecrets 依次用 fn 处理配置包中所有敏感字段
+func bundleSecrets(b *Bundle, fn func(value string) (string, error)) error {
+	var err error
+	for i := range b.Models {
+		if err := modelSecrets(&b.Models[i], fn); err != nil {
+			return err
+		}
+	}
+	if b.Settings != nil {
+		if b.Settings.WebPassword, err = fn(b.Settings.WebPassword); err != nil {
+			return err
+		}
+	}
+	for i := range b.Accounts {
+		if b.Accounts[i].UserData.Password, err = fn(b.Accounts[i].UserData.Password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export 导出配置包，环境变量和命令行参数覆盖的值不会导出
+func (c *Config) Export(opts ExportOptions) (Bundle, error) {
+	c = c.top()
+	sections, err := selectSections(opts.Sections, BundleSections)
+	if err != nil {
+		return Bundle{}, err
+	}
+	if opts.Secrets == "" {
+		opts.Secrets = SecretsRedact
+	}
+
+	c.mu.RLock()
+	f := c.current()
+	c.restoreOverridden(&f)
+	c.mu.RUnlock()
+
+	b := Bundle{
+		Format:     bundleFormat,
+		Version:    bundleVersion,
+		ExportedAt: time.Now().Unix(),
+		Sections:   sections,
+		Secrets:    opts.Secrets,
+	}
+	if slices.Contains(sections, SectionModels) {
+		b.Models = slices.Clone(f.Models)
+	}
+	if slices.Contains(sections, SectionSettings) {
+		b.Settings = &BundleSettings{
+			Debug:       f.Debug,
+			SubmitDelay: f.SubmitDelay,
+			Listen:      f.Listen,
+			WebPassword: f.WebPassword,
+			ChromePath:  f.ChromePath,
+			BrowserURL:  f.BrowserURL,
+			Discovery:   f.Discovery,
+			RateLimit:   f.RateLimit,
+		}
+	}
+	if slices.Contains(sections, SectionAccounts) {
+		b.Accounts = append([]Account{{
+			ID:       DefaultAccount,
+			UserData: UserData{UserName: f.UserData.UserName, Password: f.UserData.Password},
+			Courses:  slices.Clone(f.Courses),
+			Models:   slices.Clone(f.PreferredModels),
+		}}, cloneAccounts(f.Accounts)...)
+	}
+
+	switch opts.Secrets {
+	case SecretsPlain:
+	case SecretsRedact:
+		// 模型的额外请求头和代理密码显示为 ******（保留请求头名称和代理地址），其他敏感字段清空
+		for i := range b.Models {
+			b.Models[i] = MaskModelSecrets(b.Models[i])
+		}
+		if b.Settings != nil {
+			b.Settings.WebPassword = ""
+		}
+		for i := range b.Accounts {
+			b.Accounts[i].UserData.Password = ""
+		}
+	case SecretsEncrypt:
+		if opts.Passphrase == "" {
+			return Bundle{}, errors.New("加密导出需要口令")
+		}
+		box, err := newPassphraseBox(opts.Passphrase, "")
+		if err != nil {
+			return Bundle{}, err
+		}
+		if err := bundleSecrets(&b, box.encrypt); err != nil {
+			return Bundle{}, err
+		}
+		if b.Check, err = box.encrypt(bundleCheck); err != nil {
+			return Bundle{}, err
+		}
+		b.KeySalt = box.salt
+	default:
+		return Bundle{}, fmt.Errorf("未知的敏感字段处理方式: %s（可选 redact、plain、encrypt）", opts.Secrets)
+	}
+	return b, nil
+}
+
+// ParseBundle 解析配置包并检查格式和版本
+func ParseBundle(data []byte) (Bundle, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Bundle{}, fmt.Errorf("配置包格式错误: %w", err)
+	}
+	if b.Format != bundleFormat {
+		return Bundle{}, errors.New("不是有效的配置包")
+	}
+	if b.Version > bundleVersion {
+		return Bundle{}, fmt.Errorf("配置包版本 %d 高于当前程序支持的版本 %d，请升级程序", b.Version, bundleVersion)
+	}
+	return b, nil
+}
+
+// decryptBundle 使用口令解密配置包中的敏感字段
+func decryptBundle(b *Bundle, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("配置包使用口令加密，请提供口令")
+	}
+	box, err := newPassphraseBox(passphrase, b.KeySalt)
+	if err != nil {
+		return err
+	}
+	if check, err := box.decrypt(b.Check); err != nil || check != bundleCheck {
+		return errors.New("口令错误")
+	}
+	if err := bundleSecrets(b, box.decrypt); err != nil {
+		return err
+	}
+	b.Secrets = SecretsPlain
+	return nil
+}
+
+// Import 导入配置包，返回导入前后的差异；DryRun 时只返回差异
+// 模型列表和设置整体替换，账号按ID添加或更新（不删除配置包中没有的账号），为空的敏感字段保留已有的值
+// 导入的是配置文件中的值：被环境变量或命令行参数覆盖的字段照常写入配置文件，但本次运行中仍使用覆盖值，差异中标出其来源
+func (c *Config) Import(b Bundle, opts ImportOptions) ([]Change, error) {
+	c = c.top()
+	sections, err := selectSections(opts.Sections, b.Sections)
+	if err != nil {
+		return nil, err
+	}
+	b.Models = slices.Clone(b.Models)
+	b.Accounts = cloneAccounts(b.Accounts)
+	if b.Settings != nil {
+		settings := *b.Settings
+		b.Settings = &settings
+	}
+	if b.Encrypted() {
+		if err := decryptBundle(&b, opts.Passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	previous := cloneConfigFile(c.fileLayer)
+	next := cloneConfigFile(previous)
+	if slices.Contains(sections, SectionModels) {
+		next.Models = importModels(previous.Models, b.Models)
+	}
+	if slices.Contains(sections, SectionSettings) && b.Settings != nil {
+		importSettings(&next, *b.Settings)
+	}
+	if slices.Contains(sections, SectionAccounts) {
+		importAccounts(&next, b.Accounts)
+	}
+
+	if errs := validateConfigFile(next); len(errs) > 0 {
+		c.mu.Unlock()
+		return nil, ValidationErrors(errs)
+	}
+	changes := diffConfig(previous, next)
+	overridden := c.overriddenFields()
+	for i := range changes {
+		changes[i].Overridden = overridden[changes[i].Field]
+	}
+	if opts.DryRun || len(changes) == 0 {
+		c.mu.Unlock()
+		return changes, nil
+	}
+
+	before := c.current()
+	c.setEffective(c.replaceFileLayer(next))
+	err = c.saveInternal()
+	current := c.current()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	c.refreshAccounts()
+	c.notify(before, current)
+	return changes, nil
+}
+
+// importModels 替换模型列表，为空或隐藏的敏感字段保留同名模型已有的值
+func importModels(existing, imported []ModelConfig) []ModelConfig {
+	return RestoreModelSecrets(imported, existing)
+}
+
+// MaskModelSecrets 隐藏模型中的敏感字段，用于在界面中显示和导出：API Key 清空，额外请求头的值和代理密码显示为 ******
+func MaskModelSecrets(m ModelConfig) ModelConfig {
+	m.APIKey = ""
+	if len(m.ExtraHeaders) > 0 {
+		headers := make(map[string]string, len(m.ExtraHeaders))
+		for name := range m.ExtraHeaders {
+			headers[name] = redactedSecret
+		}
+		m.ExtraHeaders = headers
+	}
+	m.Proxy = maskProxyPassword(m.Proxy)
+	return m
+}
+
+// maskProxyPassword 代理地址中的密码显示为 ******（url.UserPassword 会转义 *，这里直接拼接）
+func maskProxyPassword(proxy string) string {
+	if !proxyHasPassword(proxy) {
+		return proxy
+	}
+	u, _ := url.Parse(proxy)
+	user := url.User(u.User.Username())
+	u.User = user
+	return strings.Replace(u.String(), user.String()+"@", user.String()+":"+redactedSecret+"@", 1)
+}
+
+// RestoreModelSecrets 将界面提交或导入的模型中为空的 API Key、显示为 ****** 的请求头和代理密码还原为同名模型已保存的值
+// 已保存的模型中没有对应的值时，删除该请求头或代理密码
+func RestoreModelSecrets(models, saved []ModelConfig) []ModelConfig {
+	result := cloneModels(models)
+	for i := range result {
+		m := &result[i]
+		var old ModelConfig
+		if j := slices.IndexFunc(saved, func(s ModelConfig) bool { return s.Name == m.Name }); j >= 0 {
+			old = saved[j]
+		}
+
+		if m.APIKey == "" {
+			m.APIKey = old.APIKey
+		}
+		for name, value := range m.ExtraHeaders {
+			if value != redactedSecret {
+				continue
+			}
+			if previous, ok := old.ExtraHeaders[name]; ok {
+				m.ExtraHeaders[name] = previous
+			} else {
+				delete(m.ExtraHeaders, name)
+			}
+		}
+		m.Proxy = restoreProxyPassword(m.Proxy, old.Proxy)
+	}
+	return result
+}
+
+// restoreProxyPassword 代理地址中的密码为 ****** 时使用 previous 中的密码
+func restoreProxyPassword(proxy, previous string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
+	}
+	if password, ok := u.User.Password(); !ok || password != redactedSecret {
+		return proxy
+	}
+	u.User = url.User(u.User.Username())
+	if old, err := url.Parse(previous); err == nil && old.User != nil {
+		if password, ok := old.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), password)
+		}
+	}
+	return u.String()
+}
+
+// importSettings 替换设置，为空的 Web 访问密码保留已有的值
+func importSettings(f *ConfigFile, s BundleSettings) {
+	f.Debug = s.Debug
+	f.SubmitDelay = s.SubmitDelay
+	f.Listen = s.Listen
+	if s.WebPassword != "" {
+		f.WebPassword = s.WebPassword
+	}
+	f.ChromePath = s.ChromePath
+	f.BrowserURL = s.BrowserURL
+	f.Discovery = s.Discovery
+	f.RateLimit = s.RateLimit
+}
+
+// importAccounts 按ID添加或更新账号，为空的密码保留已有的值
+func importAccounts(f *ConfigFile, accounts []Account) {
+	for _, a := range accounts {
+		if a.ID == DefaultAccount {
+			if a.UserData.Password == "" {
+				a.UserData.Password = f.UserData.Password
+			}
+			f.UserData = UserData{UserName: a.UserData.UserName, Password: a.UserData.Password}
+			f.Courses = a.Courses
+			f.PreferredModels = a.Models
+			continue
+		}
+
+		a.Name = strings.TrimSpace(a.Name)
+		i := slices.IndexFunc(f.Accounts, func(e Account) bool { return e.ID == a.ID })
+		if i < 0 {
+			f.Accounts = append(f.Accounts, a)
+			continue
+		}
+		if a.UserData.Password == "" {
+			a.UserData.Password = f.Accounts[i].UserData.Password
+		}
+		f.Accounts[i] = a
+	}
+}
+
+// diffConfig 比较两份配置，模型按名称、账号和课程按ID对应，敏感字段不显示原值
+func diffConfig(previous, next ConfigFile) []Change {
+	before := make(map[string]string)
+	after := make(map[string]string)
+	flattenConfig("", toJSONValue(previous), before)
+	flattenConfig("", toJSONValue(next), after)
+
+	changes := []Change{}
+	for field, old := range before {
+		if value, ok := after[field]; !ok {
+			changes = append(changes, Change{Field: field, Action: "remove", Old: maskSecret(field, old)})
+		} else if value != old {
+			changes = append(changes, Change{Field: field, Action: "update", Old: maskSecret(field, old), New: maskSecret(field, value)})
+		}
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok {
+			changes = append(changes, Change{Field: field, Action: "add", New: maskSecret(field, value)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// toJSONValue 转换为 JSON 的通用结构（map / slice / 标量）
+func toJSONValue(f ConfigFile) any {
+	f.SchemaVersion = 0
+	f.KeySource, f.KeySalt = "", ""
+	data, _ := json.Marshal(f)
+	var value any
+	json.Unmarshal(data, &value)
+	return value
+}
+
+// flattenConfig 将配置展开为 字段路径 → 值
+func flattenConfig(prefix string, value any, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenConfig(path, item, out)
+		}
+	case []any:
+		for i, item := range v {
+			flattenConfig(prefix+"["+elementKey(item, i)+"]", item, out)
+		}
+	case nil:
+	case string:
+		if v != "" {
+			out[prefix] = v
+		}
+	default:
+		data, _ := json.Marshal(v)
+		out[prefix] = string(data)
+	}
+}
+
+// elementKey 列表元素的标识：有 id 时用 id，有 name 时用 name，否则用序号
+func elementKey(item any, index int) string {
+	if m, ok := item.(map[string]any); ok {
+		for _, key := range []string{"id", "name"} {
+			if id, ok := m[key].(string); ok && id != "" {
+				return id
+			}
+		}
+	}
+	return strconv.Itoa(index)
+}
+
+// maskSecret 敏感字段只显示是否有值，代理地址只隐藏密码
+func maskSecret(field, value string) string {
+	name := field[strings.LastIndex(field, ".")+1:]
+	switch {
+	case value == "":
+		return value
+	case name == "api_key" || name == "password" || name == "web_password" || strings.Contains(field, ".extra_headers."):
+		return redactedSecret
+	case name == "proxy":
+		return maskProxyPassword(value)
+	}
+	return value
+}
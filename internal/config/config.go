@@ -4,14 +4,23 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"mosoteach/internal/state"
 )
 
 // ModelConfig 模型配置
@@ -21,21 +30,45 @@ type ModelConfig struct {
 	BaseURL string `json:"base_url"`
 	APIKey  string `json:"api_key"`
 	Model   string `json:"model"`
+
+	// 以下为可选的请求参数，为空时使用默认值
+	Temperature  *float64          `json:"temperature,omitempty"`   // 采样温度（默认 0.1）
+	MaxTokens    int               `json:"max_tokens,omitempty"`    // 最大输出 token 数（默认 1000）
+	Timeout      int               `json:"timeout,omitempty"`       // 单次请求超时（秒，默认 60）
+	ExtraHeaders map[string]string `json:"extra_headers,omitempty"` // 额外的请求头
+	ExtraBody    map[string]any    `json:"extra_body,omitempty"`    // 合并到请求体的额外参数，如关闭思考模式
+	Proxy        string            `json:"proxy,omitempty"`         // 代理地址（http://、https:// 或 socks5://）
+}
+
+// cloneModels 复制模型列表，包括温度指针和额外参数
+func cloneModels(models []ModelConfig) []ModelConfig {
+	result := slices.Clone(models)
+	for i := range result {
+		if t := result[i].Temperature; t != nil {
+			temperature := *t
+			result[i].Temperature = &temperature
+		}
+		result[i].ExtraHeaders = maps.Clone(result[i].ExtraHeaders)
+		result[i].ExtraBody = maps.Clone(result[i].ExtraBody)
+	}
+	return result
 }
 
+// SavedCookie 保存的浏览器Cookie（带作用域和过期时间）
+type SavedCookie = state.SavedCookie
+
 // UserData 用户配置
 type UserData struct {
 	UserName string `json:"user_name"`
 	Password string `json:"password"`
-	Cookie   string `json:"Cookie"`
 }
 
-// GetPassword 获取密码
+// GetPassword 获取密码（内存中为明文，保存到磁盘时加密）
 func (u *UserData) GetPassword() string {
 	return u.Password
 }
 
-// SetPassword 设置密码
+// SetPassword 设置密码（保存到磁盘时加密）
 func (u *UserData) SetPassword(password string) {
 	u.Password = password
 }
@@ -45,40 +78,149 @@ func (u *UserData) HasPassword() bool {
 	return u.Password != ""
 }
 
+// QuizMeta 题库元数据（从互动列表和测验确认页解析）
+type QuizMeta = state.QuizMeta
+
 // CachedQuiz 缓存的题库
-type CachedQuiz struct {
-	URL        string `json:"url"`
-	CourseID   string `json:"course_id"`
-	CourseName string `json:"course_name"`
-	QuizID     string `json:"quiz_id"`
-	Name       string `json:"name"`
-	Completed  bool   `json:"completed"`
+type CachedQuiz = state.CachedQuiz
+
+// CompletionRecord 题库完成记录
+type CompletionRecord = state.CompletionRecord
+
+// RunRecord 答题运行记录
+type RunRecord = state.RunRecord
+
+// QuizKey 题库的稳定标识（课程ID/题库ID）
+func QuizKey(courseID, quizID string) string {
+	return state.QuizKey(courseID, quizID)
 }
 
+// 课程筛选方式
+const (
+	CourseFilterDefault = ""        // 默认：开放课程参与获取和答题
+	CourseFilterInclude = "include" // 包含：设置后只处理包含的课程（归档课程也可以包含）
+	CourseFilterExclude = "exclude" // 排除：始终跳过
+)
+
+// CourseStatusOpen 开放中的课程状态
+const CourseStatusOpen = "OPEN"
+
+// CourseSetting 课程的用户设置，保存在配置文件中（只保存修改过设置的课程）
+type CourseSetting struct {
+	ID     string `json:"id"`
+	Filter string `json:"filter,omitempty"` // 筛选方式：include / exclude
+	Pinned bool   `json:"pinned,omitempty"` // 置顶：优先获取和答题
+}
+
+// DiscoveredCourse 从课程列表页发现的课程，保存在状态文件中，每次刷新都会更新
+type DiscoveredCourse = state.Course
+
+// Course 课程信息（发现的课程加上用户设置）
+type Course struct {
+	ID          string
+	Name        string
+	Teacher     string
+	Status      string // 课程状态（OPEN 为开放，其他为已归档/结课）
+	URL         string // 课程互动页面地址
+	LastRefresh int64  // 上次刷新题库的时间（Unix 秒）
+	Filter      string // 筛选方式：include / exclude
+	Pinned      bool   // 置顶：优先获取和答题
+}
+
+// IsOpen 课程是否开放中
+func (c Course) IsOpen() bool {
+	return c.Status == CourseStatusOpen
+}
+
+// newCourse 合并发现的课程和用户设置
+func newCourse(d DiscoveredCourse, setting CourseSetting) Course {
+	return Course{
+		ID:          d.ID,
+		Name:        d.Name,
+		Teacher:     d.Teacher,
+		Status:      d.Status,
+		URL:         d.URL,
+		LastRefresh: d.LastRefresh,
+		Filter:      setting.Filter,
+		Pinned:      setting.Pinned,
+	}
+}
+
+// CourseCache 课程的增量刷新记录
+type CourseCache = state.CourseCache
+
 // ConfigFile 配置文件结构
 type ConfigFile struct {
-	UserData      UserData      `json:"user_data"`
-	Models        []ModelConfig `json:"models"`
-	CachedQuizzes []CachedQuiz  `json:"cached_quizzes,omitempty"`
-	CompletedURLs []string      `json:"completed_urls,omitempty"`
-	Debug         bool          `json:"debug,omitempty"`
-	SubmitDelay   int           `json:"submit_delay,omitempty"` // 提交延迟（秒）
-	WebPassword   string        `json:"web_password,omitempty"` // Web 访问密码
+	SchemaVersion   int             `json:"schema_version"` // 配置文件结构版本，旧版配置在加载时自动升级
+	UserData        UserData        `json:"user_data"`
+	Models          []ModelConfig   `json:"models"`
+	Courses         []CourseSetting `json:"courses,omitempty"` // 课程筛选和置顶设置
+	Debug           bool            `json:"debug,omitempty"`
+	SubmitDelay     int             `json:"submit_delay,omitempty"`     // 提交延迟（秒）
+	Listen          string          `json:"listen,omitempty"`           // Web 服务监听地址（默认 :11451）
+	WebPassword     string          `json:"web_password,omitempty"`     // Web 访问密码
+	ChromePath      string          `json:"chrome_path,omitempty"`      // 本地 Chrome 路径（为空时自动查找）
+	BrowserURL      string          `json:"browser_url,omitempty"`      // 远程 DevTools 地址（ws:// 或 http://host:9222）
+	Discovery       string          `json:"discovery,omitempty"`        // 题库发现方式：auto / http / browser
+	RateLimit       *RateLimit      `json:"rate_limit,omitempty"`       // HTTP 请求限速
+	KeySource       string          `json:"key_source,omitempty"`       // 敏感字段的密钥来源：keyfile / env / passphrase
+	KeySalt         string          `json:"key_salt,omitempty"`         // 口令派生密钥使用的盐
+	PreferredModels []string        `json:"preferred_models,omitempty"` // 默认账号偏好的模型名称
+	Accounts        []Account       `json:"accounts,omitempty"`         // 默认账号之外的其他账号
+}
+
+// RateLimit HTTP 获取题库时的限速配置
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"` // 每秒请求数（0 使用默认值，负数表示不限速）
+	Burst             int     `json:"burst"`               // 允许的突发请求数
+	Concurrency       int     `json:"concurrency"`         // 同时获取的页面数
 }
 
+// 默认限速：平均每 2 秒一个请求，最多同时获取 2 个页面
+const (
+	defaultRequestsPerSecond = 0.5
+	defaultBurst             = 2
+	defaultConcurrency       = 2
+)
+
 // Config 全局配置管理
 type Config struct {
 	mu               sync.RWMutex
 	UserData         UserData
 	Models           []ModelConfig
-	CachedQuizzes    []CachedQuiz
+	Courses          []CourseSetting
+	PreferredModels  []string  // 账号偏好的模型名称
+	Accounts         []Account // 默认账号之外的其他账号
 	FilePath         string
+	StatePath        string // 状态文件路径（登录会话、题库缓存、完成记录等）
+	KeyPath          string // 密钥文件路径
 	ChromeBinaryPath string
 	IsLinux          bool
-	CompletedURLs    map[string]bool
 	Debug            bool
-	SubmitDelay      int    // 提交延迟（秒）
-	WebPassword      string // Web 访问密码
+	SubmitDelay      int        // 提交延迟（秒）
+	WebPassword      string     // Web 访问密码
+	BrowserURL       string     // 远程 DevTools 地址，设置后不再启动本地 Chrome
+	Discovery        string     // 题库发现方式，为空时自动选择
+	RateLimit        *RateLimit // HTTP 请求限速，为空时使用默认值
+	Listen           string     // Web 服务监听地址
+	state            state.Store
+	loadedFrom       string // 上次加载配置使用的文件
+	passphrase       string // 启动时输入的口令
+	box              *secretBox
+	flags            map[string]string        // 命令行参数指定的配置值
+	sources          map[string]string        // 每个配置项当前生效值的来源
+	fileLayer        ConfigFile               // 配置文件中的值（未应用环境变量和命令行参数）
+	modelOverrides   map[string]modelOverride // 被覆盖的模型，按模型名称对应
+
+	subMu          sync.Mutex
+	subscribers    map[int]Subscriber // 配置变化的订阅者
+	nextSubscriber int
+
+	root        *Config // 账号视图所属的配置，默认账号为空
+	accountID   string
+	accountName string
+	viewMu      sync.Mutex
+	views       map[string]*Config // 已打开的账号视图
 }
 
 var (
@@ -86,14 +228,23 @@ var (
 	once     sync.Once
 )
 
-// GetConfig 获取配置单例
+// New 创建配置，使用默认路径（./user_data.json），调用 Load 后才会读取文件
+// 各组件通过构造函数接收配置，同一进程中可以同时存在多份配置
+func New() *Config {
+	c := &Config{
+		Models: getDefaultModels(),
+	}
+	c.initPaths()
+	c.state = state.NewFileStore(c.StatePath)
+	return c
+}
+
+// GetConfig 获取进程级的默认配置
+//
+// Deprecated: 仅为兼容旧的调用方式保留，请使用 New 创建配置并显式传递给各组件
 func GetConfig() *Config {
 	once.Do(func() {
-		instance = &Config{
-			CompletedURLs: make(map[string]bool),
-			Models:        getDefaultModels(),
-		}
-		instance.initPaths()
+		instance = New()
 	})
 	return instance
 }
@@ -150,123 +301,191 @@ func getDefaultModels() []ModelConfig {
 func (c *Config) initPaths() {
 	c.IsLinux = runtime.GOOS == "linux"
 	c.FilePath = "./user_data.json"
+	c.StatePath = "./state.json"
+	c.KeyPath = "./user_data.key"
+}
 
-	if c.IsLinux {
-		c.ChromeBinaryPath = c.findChromeBinary()
-	} else {
-		c.ChromeBinaryPath = c.findWindowsChrome()
+// Load 加载配置文件
+func (c *Config) Load() error {
+	c = c.top()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.loadLocked(); err != nil {
+		return err
 	}
+	return c.finishRotation()
 }
 
-// findWindowsChrome Windows 下自动查找 Chrome 二进制文件
-func (c *Config) findWindowsChrome() string {
-	paths := []string{
-		// 常见安装路径
-		os.Getenv("PROGRAMFILES") + "\\Google\\Chrome\\Application\\chrome.exe",
-		os.Getenv("PROGRAMFILES(X86)") + "\\Google\\Chrome\\Application\\chrome.exe",
-		os.Getenv("LOCALAPPDATA") + "\\Google\\Chrome\\Application\\chrome.exe",
-		// 便携版常见路径
-		".\\chrome-win64\\chrome.exe",
-		"..\\chrome-win64\\chrome.exe",
+// loadLocked Load 的实现（调用方持有锁）
+func (c *Config) loadLocked() error {
+	// 旧版配置在内存中升级到当前结构，状态文件打开后再升级磁盘上的文件
+	configFile, source, err := readConfigFile(c.FilePath, c.decryptable)
+	if err != nil && !os.IsNotExist(err) {
+		return err
 	}
 
-	for _, path := range paths {
-		if _, err := os.Stat(path); err == nil {
-			return path
+	// 加载密钥（只在首次加载时），状态文件中的登录会话也使用该密钥加密
+	if c.box == nil {
+		box, err := c.resolveSecretBox(configFile)
+		if err != nil {
+			return err
+		}
+		if err := c.state.SetCipher(box); err != nil {
+			return err
 		}
+		c.box = box
 	}
-	return ""
-}
-
-// findChromeBinary Linux下自动查找Chrome
-func (c *Config) findChromeBinary() string {
-	binaries := []string{
-		"/usr/bin/google-chrome",
-		"/usr/bin/google-chrome-stable",
-		"/usr/bin/chromium-browser",
-		"/usr/bin/chromium",
-		"/snap/bin/chromium",
+	if err := c.state.Open(); err != nil {
+		return err
+	}
+	if err := upgradeConfigFile(c.FilePath, c.importMoved); err != nil {
+		return err
 	}
 
-	for _, binary := range binaries {
-		if _, err := os.Stat(binary); err == nil {
-			return binary
-		}
+	plaintext, err := c.box.decryptSecrets(&configFile)
+	if err != nil {
+		return fmt.Errorf("解密配置失败: %w", err)
 	}
-	return ""
-}
 
-// Load 加载配置文件
-func (c *Config) Load() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	// 如果配置文件中有模型配置则使用，否则使用默认
+	fileModels := len(configFile.Models) > 0
+	if !fileModels {
+		configFile.Models = getDefaultModels()
+	}
 
-	data, err := os.ReadFile(c.FilePath)
+	// 在配置文件之上应用环境变量和命令行参数
+	effective := cloneConfigFile(configFile)
+	sources, err := c.applyOverrides(&effective, source != "", fileModels)
 	if err != nil {
-		// 文件不存在，使用默认配置
-		if os.IsNotExist(err) {
-			c.Models = getDefaultModels()
-			return c.saveInternal()
-		}
 		return err
 	}
-
-	var configFile ConfigFile
-	if err := json.Unmarshal(data, &configFile); err != nil {
-		return err
+	c.apply(effective, configFile, sources)
+	for _, e := range validateConfigFile(effective) {
+		slog.Warn("配置项无效，修改配置前请先更正", "field", e.Field, "error", e.Message)
 	}
 
-	c.UserData = configFile.UserData
-	c.CachedQuizzes = configFile.CachedQuizzes
+	// 文件不存在，保存默认配置
+	if source == "" {
+		c.logSource("默认配置")
+		return c.saveInternal()
+	}
 
-	// 如果配置文件中有模型配置则使用，否则使用默认
-	if len(configFile.Models) > 0 {
-		c.Models = configFile.Models
-	} else {
-		c.Models = getDefaultModels()
+	c.logSource(source)
+	if source != c.FilePath {
+		// 主文件损坏，保留损坏的文件以便排查，并用备份恢复主文件
+		slog.Warn("配置文件损坏，已从备份恢复", "file", c.FilePath, "backup", source)
+		if err := os.Rename(c.FilePath, c.FilePath+".corrupt"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := c.saveInternal(); err != nil {
+			return err
+		}
 	}
 
-	// 加载已完成的URL列表
-	for _, url := range configFile.CompletedURLs {
-		c.CompletedURLs[url] = true
+	// 旧版明文保存的敏感字段改为加密保存
+	if plaintext {
+		if err := c.saveInternal(); err != nil {
+			return err
+		}
+		removePlaintextBackups(c.FilePath)
+		slog.Info("已加密配置文件中的敏感字段", "key_source", c.box.source)
 	}
+	return nil
+}
+
+// apply 替换当前生效的配置（调用方持有锁）
+// effective 为应用环境变量和命令行参数之后的配置，fileLayer 为配置文件中的值
+func (c *Config) apply(effective, fileLayer ConfigFile, sources map[string]string) {
+	c.setEffective(effective)
+	c.fileLayer = cloneConfigFile(fileLayer)
+	c.sources = sources
+	c.modelOverrides = modelOverridesOf(effective, fileLayer, sources)
+}
+
+// setEffective 替换当前生效的配置值，各配置项的来源不变（调用方持有锁）
+func (c *Config) setEffective(effective ConfigFile) {
+	c.UserData = effective.UserData
+	c.Courses = effective.Courses
+	c.Models = effective.Models
+	c.PreferredModels = effective.PreferredModels
+	c.Accounts = effective.Accounts
 
 	// 加载调试模式配置并设置日志级别
-	c.Debug = configFile.Debug
-	setupLogger(c.Debug)
+	if effective.Debug != c.Debug || c.sources == nil {
+		setupLogger(effective.Debug)
+	}
+	c.Debug = effective.Debug
 
 	// 加载提交延迟配置
-	c.SubmitDelay = configFile.SubmitDelay
+	c.SubmitDelay = effective.SubmitDelay
 
 	// 加载 Web 密码
-	c.WebPassword = configFile.WebPassword
+	c.WebPassword = effective.WebPassword
 
-	return nil
+	// 加载浏览器配置
+	c.ChromeBinaryPath = effective.ChromePath
+	c.BrowserURL = effective.BrowserURL
+
+	c.Discovery = effective.Discovery
+	c.RateLimit = effective.RateLimit
+	c.Listen = effective.Listen
+}
+
+// current 当前生效的配置（调用方持有锁）
+func (c *Config) current() ConfigFile {
+	return ConfigFile{
+		SchemaVersion:   currentSchemaVersion,
+		UserData:        c.UserData,
+		Models:          c.Models,
+		Courses:         c.Courses,
+		Debug:           c.Debug,
+		SubmitDelay:     c.SubmitDelay,
+		WebPassword:     c.WebPassword,
+		ChromePath:      c.ChromeBinaryPath,
+		BrowserURL:      c.BrowserURL,
+		Discovery:       c.Discovery,
+		RateLimit:       c.RateLimit,
+		Listen:          c.Listen,
+		PreferredModels: c.PreferredModels,
+		Accounts:        c.Accounts,
+	}
+}
+
+// logSource 记录加载配置使用的文件（只在来源变化时输出，调用方持有锁）
+func (c *Config) logSource(source string) {
+	if source == c.loadedFrom {
+		return
+	}
+	c.loadedFrom = source
+	slog.Info("已加载配置", "file", source)
 }
 
 // Save 保存配置文件
 func (c *Config) Save() error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.saveInternal()
 }
 
 // saveInternal 内部保存方法（不加锁）
 func (c *Config) saveInternal() error {
-	// 将 map 转换为 slice
-	var completedURLs []string
-	for url := range c.CompletedURLs {
-		completedURLs = append(completedURLs, url)
+	if c.root != nil {
+		return c.root.saveAccount(c.account())
 	}
+	configFile := c.current()
+
+	// 来自环境变量和命令行参数的值不写入配置文件
+	c.restoreOverridden(&configFile)
+	c.fileLayer = cloneConfigFile(configFile)
+	c.updateModelSources()
 
-	configFile := ConfigFile{
-		UserData:      c.UserData,
-		Models:        c.Models,
-		CachedQuizzes: c.CachedQuizzes,
-		CompletedURLs: completedURLs,
-		Debug:         c.Debug,
-		SubmitDelay:   c.SubmitDelay,
-		WebPassword:   c.WebPassword,
+	// 敏感字段加密后写入磁盘
+	if c.box == nil {
+		return errors.New("密钥未加载，无法保存配置")
+	}
+	if err := c.box.encryptSecrets(&configFile); err != nil {
+		return fmt.Errorf("加密配置失败: %w", err)
 	}
 
 	data, err := json.MarshalIndent(configFile, "", "    ")
@@ -274,43 +493,139 @@ func (c *Config) saveInternal() error {
 		return err
 	}
 
-	return os.WriteFile(c.FilePath, data, 0644)
+	return writeConfigFile(c.FilePath, data)
+}
+
+// State 运行状态存储
+func (c *Config) State() state.Store {
+	return c.state
+}
+
+// GetCookie 获取 name=value 形式的Cookie字符串
+func (c *Config) GetCookie() string {
+	return c.state.Session().Cookie
 }
 
 // UpdateCookie 更新Cookie
 func (c *Config) UpdateCookie(cookie string) error {
-	c.mu.Lock()
-	c.UserData.Cookie = cookie
-	c.mu.Unlock()
-	return c.Save()
+	session := c.state.Session()
+	session.Cookie = cookie
+	return c.state.SaveSession(session)
+}
+
+// UpdateCookies 更新结构化Cookie，同时生成 name=value 形式的Cookie字符串
+func (c *Config) UpdateCookies(cookies []SavedCookie) error {
+	parts := make([]string, 0, len(cookies))
+	for _, ck := range cookies {
+		parts = append(parts, ck.Name+"="+ck.Value)
+	}
+	return c.state.SaveSession(state.Session{Cookie: strings.Join(parts, "; "), Cookies: cookies})
+}
+
+// ClearCookies 清除保存的Cookie（账号变更时调用）
+func (c *Config) ClearCookies() error {
+	return c.state.SaveSession(state.Session{})
+}
+
+// GetSavedCookies 获取未过期的结构化Cookie
+func (c *Config) GetSavedCookies() []SavedCookie {
+	now := time.Now()
+	var valid []SavedCookie
+	for _, ck := range c.state.Session().Cookies {
+		if !ck.IsExpired(now) {
+			valid = append(valid, ck)
+		}
+	}
+	return valid
 }
 
-// AddCompletedURL 添加已完成的URL
-func (c *Config) AddCompletedURL(url string) error {
+// CookieExpiry 获取最早过期的持久Cookie的过期时间（没有持久Cookie时返回零值）
+func (c *Config) CookieExpiry() time.Time {
+	var earliest time.Time
+	for _, ck := range c.state.Session().Cookies {
+		if ck.Expires <= 0 {
+			continue
+		}
+		t := time.Unix(int64(ck.Expires), 0)
+		if earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+	}
+	return earliest
+}
+
+// IsQuizCompleted 检查题库是否已完成，url 为可选的答题地址（用于兼容旧版记录）
+func (c *Config) IsQuizCompleted(courseID, quizID, url string) bool {
+	return c.state.IsCompleted(courseID, quizID, url)
+}
+
+// GetCompletionRecords 获取完成记录（按完成时间排序）
+func (c *Config) GetCompletionRecords() []CompletionRecord {
+	return c.state.Completions()
+}
+
+// AddRunRecord 添加答题运行记录
+func (c *Config) AddRunRecord(run RunRecord) error {
+	return c.state.AddRun(run)
+}
+
+// GetRunHistory 获取最近的答题运行记录（新的在前）
+func (c *Config) GetRunHistory(limit int) []RunRecord {
+	return c.state.Runs(limit)
+}
+
+// GetUserData 获取账号配置
+func (c *Config) GetUserData() UserData {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.UserData
+}
+
+// UpdateAccount 更新账号和密码（为空的字段保持不变）并保存，返回账号是否有变化
+func (c *Config) UpdateAccount(userName, password string) (bool, error) {
+	if errs := validateUserData("", UserData{UserName: userName}); len(errs) > 0 {
+		return false, ValidationErrors(errs)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.CompletedURLs[url] {
-		return nil
+	changed := (userName != "" && userName != c.UserData.UserName) ||
+		(password != "" && password != c.UserData.GetPassword())
+	if userName != "" {
+		c.UserData.UserName = userName
 	}
+	if password != "" {
+		c.UserData.SetPassword(password)
+	}
+	return changed, c.saveInternal()
+}
 
-	c.CompletedURLs[url] = true
-	return c.saveInternal()
+// GetModels 获取所有模型配置
+func (c *Config) GetModels() []ModelConfig {
+	c = c.top()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return cloneModels(c.Models)
 }
 
-// IsURLCompleted 检查URL是否已完成
-func (c *Config) IsURLCompleted(url string) bool {
+// GetChromePath 获取本地 Chrome 路径（为空时自动查找）
+func (c *Config) GetChromePath() string {
+	c = c.top()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.CompletedURLs[url]
+	return c.ChromeBinaryPath
 }
 
 // GetMaskedUsername 获取脱敏用户名
 func (c *Config) GetMaskedUsername() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return MaskUsername(c.UserData.UserName)
+}
 
-	username := c.UserData.UserName
+// MaskUsername 脱敏用户名（11 位手机号隐藏中间 4 位）
+func MaskUsername(username string) string {
 	if len(username) == 11 {
 		return username[:3] + "****" + username[7:]
 	}
@@ -326,77 +641,243 @@ func (c *Config) GetAbsPath(relativePath string) string {
 	return absPath
 }
 
-// GetEnabledModels 获取已启用的模型列表
+// GetEnabledModels 获取账号可用的模型列表（已启用的模型按账号的模型偏好筛选和排序）
 func (c *Config) GetEnabledModels() []ModelConfig {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
+	preferred := c.PreferredModels
+	c.mu.RUnlock()
 
 	var enabled []ModelConfig
-	for _, m := range c.Models {
+	for _, m := range c.top().GetModels() {
 		if m.Enabled && m.APIKey != "" {
 			enabled = append(enabled, m)
 		}
 	}
-	return enabled
+	return preferModels(enabled, preferred)
 }
 
-// UpdateModels 更新模型配置
+// UpdateModels 更新模型配置，验证不通过时返回 ValidationErrors
 func (c *Config) UpdateModels(models []ModelConfig) error {
+	c = c.top()
 	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check(func(f *ConfigFile) { f.Models = models }); err != nil {
+		return err
+	}
 	c.Models = models
-	c.mu.Unlock()
-	return c.Save()
+	return c.saveInternal()
 }
 
-// AddModel 添加新模型
+// AddModel 添加新模型，验证不通过时返回 ValidationErrors
 func (c *Config) AddModel(model ModelConfig) error {
+	c = c.top()
 	c.mu.Lock()
-	c.Models = append(c.Models, model)
-	c.mu.Unlock()
-	return c.Save()
+	defer c.mu.Unlock()
+	models := append(cloneModels(c.Models), model)
+	if err := c.check(func(f *ConfigFile) { f.Models = models }); err != nil {
+		return err
+	}
+	c.Models = models
+	return c.saveInternal()
 }
 
 // GetCachedQuizzes 获取缓存的题库
 func (c *Config) GetCachedQuizzes() []CachedQuiz {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
 	// 更新完成状态
-	result := make([]CachedQuiz, len(c.CachedQuizzes))
-	for i, q := range c.CachedQuizzes {
-		result[i] = q
-		result[i].Completed = c.CompletedURLs[q.URL]
+	result := c.state.CachedQuizzes()
+	for i, q := range result {
+		result[i].Completed = c.state.IsCompleted(q.CourseID, q.QuizID, q.URL)
 	}
 	return result
 }
 
 // SaveCachedQuizzes 保存缓存的题库
 func (c *Config) SaveCachedQuizzes(quizzes []CachedQuiz) error {
+	return c.state.SaveCachedQuizzes(quizzes)
+}
+
+// GetCachedQuiz 按课程ID和题库ID查找缓存的题库
+func (c *Config) GetCachedQuiz(courseID, quizID string) (CachedQuiz, bool) {
+	for _, q := range c.state.CachedQuizzes() {
+		if q.CourseID == courseID && q.QuizID == quizID {
+			q.Completed = c.state.IsCompleted(q.CourseID, q.QuizID, q.URL)
+			return q, true
+		}
+	}
+	return CachedQuiz{}, false
+}
+
+// ReplaceCourseQuizzes 替换某个课程的缓存题库，其他课程保持不变
+func (c *Config) ReplaceCourseQuizzes(courseID string, quizzes []CachedQuiz) error {
+	cached := c.state.CachedQuizzes()
+	result := make([]CachedQuiz, 0, len(cached)+len(quizzes))
+	for _, q := range cached {
+		if q.CourseID != courseID {
+			result = append(result, q)
+		}
+	}
+	return c.state.SaveCachedQuizzes(append(result, quizzes...))
+}
+
+// GetCourses 获取发现的课程列表及其设置（置顶课程在前）
+func (c *Config) GetCourses() []Course {
+	c.mu.RLock()
+	settings := c.courseSettings()
+	c.mu.RUnlock()
+
+	discovered := c.state.Courses()
+	result := make([]Course, 0, len(discovered))
+	for _, d := range discovered {
+		result = append(result, newCourse(d, settings[d.ID]))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Pinned && !result[j].Pinned
+	})
+	return result
+}
+
+// courseSettings 按课程ID索引的课程设置（调用方持有锁）
+func (c *Config) courseSettings() map[string]CourseSetting {
+	settings := make(map[string]CourseSetting, len(c.Courses))
+	for _, setting := range c.Courses {
+		settings[setting.ID] = setting
+	}
+	return settings
+}
+
+// SaveCourses 合并新获取的课程列表：更新名称、教师和状态，保存到状态文件
+// refreshed 中的课程记录本次刷新时间；筛选和置顶设置保存在配置文件中，不受影响
+func (c *Config) SaveCourses(courses []DiscoveredCourse, refreshed []string, now int64) error {
 	c.mu.Lock()
-	c.CachedQuizzes = quizzes
-	c.mu.Unlock()
-	return c.Save()
+	defer c.mu.Unlock()
+
+	previous := c.state.Courses()
+	existing := make(map[string]DiscoveredCourse, len(previous))
+	for _, course := range previous {
+		existing[course.ID] = course
+	}
+
+	result := make([]DiscoveredCourse, 0, len(courses))
+	seen := make(map[string]bool, len(courses))
+	for _, course := range courses {
+		if old, ok := existing[course.ID]; ok {
+			course.LastRefresh = old.LastRefresh
+		}
+		if slices.Contains(refreshed, course.ID) {
+			course.LastRefresh = now
+		}
+		result = append(result, course)
+		seen[course.ID] = true
+	}
+	// 页面上不再出现的课程保留下来，标记为已归档
+	for _, course := range previous {
+		if !seen[course.ID] {
+			if course.Status == CourseStatusOpen {
+				course.Status = "ARCHIVED"
+			}
+			result = append(result, course)
+		}
+	}
+	return c.state.SaveCourses(result)
 }
 
-// MarkQuizCompleted 标记题库为已完成
-func (c *Config) MarkQuizCompleted(url string) {
+// UpdateCourseFlags 更新课程的筛选和置顶设置，恢复为默认设置的课程从配置文件中移除
+func (c *Config) UpdateCourseFlags(courseID string, filter *string, pinned *bool) (Course, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.CompletedURLs[url] = true
-	// 更新缓存中的状态
-	for i := range c.CachedQuizzes {
-		if c.CachedQuizzes[i].URL == url {
-			c.CachedQuizzes[i].Completed = true
-			break
+	discovered := c.state.Courses()
+	i := slices.IndexFunc(discovered, func(d DiscoveredCourse) bool { return d.ID == courseID })
+	if i < 0 {
+		return Course{}, fmt.Errorf("课程 %s 不存在，请先刷新题库", courseID)
+	}
+
+	setting := c.courseSettings()[courseID]
+	setting.ID = courseID
+	if filter != nil {
+		setting.Filter = *filter
+	}
+	if pinned != nil {
+		setting.Pinned = *pinned
+	}
+
+	settings := slices.DeleteFunc(slices.Clone(c.Courses), func(s CourseSetting) bool { return s.ID == courseID })
+	if setting.Filter != CourseFilterDefault || setting.Pinned {
+		settings = append(settings, setting)
+	}
+	c.Courses = settings
+	return newCourse(discovered[i], setting), c.saveInternal()
+}
+
+// CourseSelected 判断课程是否参与获取题库和答题
+// 排除的课程始终跳过；存在包含的课程时只处理包含的课程；否则只处理开放的课程
+func (c *Config) CourseSelected(courseID string, open bool) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	filter := CourseFilterDefault
+	hasInclude := false
+	for _, course := range c.Courses {
+		if course.ID == courseID {
+			filter = course.Filter
 		}
+		if course.Filter == CourseFilterInclude {
+			hasInclude = true
+		}
+	}
+
+	switch {
+	case filter == CourseFilterExclude:
+		return false
+	case hasInclude:
+		return filter == CourseFilterInclude
+	default:
+		return open
 	}
 }
 
+// IsCoursePinned 判断课程是否置顶
+func (c *Config) IsCoursePinned(courseID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, course := range c.Courses {
+		if course.ID == courseID {
+			return course.Pinned
+		}
+	}
+	return false
+}
+
+// GetCourseCache 获取课程的增量刷新记录
+func (c *Config) GetCourseCache(courseID string) (CourseCache, bool) {
+	return c.state.CourseCache(courseID)
+}
+
+// UpdateCourseCache 更新课程的增量刷新记录
+func (c *Config) UpdateCourseCache(entries map[string]CourseCache) error {
+	return c.state.UpdateCourseCache(entries)
+}
+
+// MarkQuizCompleted 记录题库已完成
+func (c *Config) MarkQuizCompleted(rec CompletionRecord) error {
+	return c.state.AddCompletion(rec)
+}
+
+// LookupAnswer 从题库答案缓存中查找答案
+func (c *Config) LookupAnswer(key string) (state.BankAnswer, bool) {
+	return c.state.LookupAnswer(key)
+}
+
+// SaveAnswers 将答案写入题库答案缓存
+func (c *Config) SaveAnswers(answers map[string]state.BankAnswer) error {
+	return c.state.SaveAnswers(answers)
+}
+
 // ValidationError 配置验证错误
 type ValidationError struct {
-	Field   string
-	Message string
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 func (e ValidationError) Error() string {
@@ -429,13 +910,10 @@ func (c *Config) ValidateUserData() []ValidationError {
 
 // ValidateModels 验证模型配置
 func (c *Config) ValidateModels() []ValidationError {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
 	var errors []ValidationError
 
 	hasEnabled := false
-	for i, m := range c.Models {
+	for i, m := range c.top().GetModels() {
 		if m.Enabled {
 			hasEnabled = true
 			if m.APIKey == "" {
@@ -464,14 +942,19 @@ func (c *Config) ValidateModels() []ValidationError {
 			Field:   "models",
 			Message: "至少需要启用一个模型",
 		})
+	} else if len(errors) == 0 && len(c.GetEnabledModels()) == 0 {
+		errors = append(errors, ValidationError{
+			Field:   "preferred_models",
+			Message: "账号偏好的模型均未启用",
+		})
 	}
 
 	return errors
 }
 
-// Validate 验证所有配置
+// Validate 验证所有配置：配置项的取值，以及账号和模型是否可以开始答题
 func (c *Config) Validate() []ValidationError {
-	var errors []ValidationError
+	errors := c.top().validateCurrent()
 	errors = append(errors, c.ValidateUserData()...)
 	errors = append(errors, c.ValidateModels()...)
 	return errors
@@ -509,21 +992,70 @@ func setupLogger(debug bool) {
 
 // GetSubmitDelay 获取提交延迟（秒）
 func (c *Config) GetSubmitDelay() int {
+	c = c.top()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.SubmitDelay
 }
 
-// SetSubmitDelay 设置提交延迟（秒）
+// SetSubmitDelay 设置提交延迟（秒），验证不通过时返回 ValidationErrors
 func (c *Config) SetSubmitDelay(delay int) error {
+	c = c.top()
 	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check(func(f *ConfigFile) { f.SubmitDelay = delay }); err != nil {
+		return err
+	}
 	c.SubmitDelay = delay
-	c.mu.Unlock()
-	return c.Save()
+	return c.saveInternal()
+}
+
+// GetBrowserURL 获取远程浏览器地址
+func (c *Config) GetBrowserURL() string {
+	c = c.top()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.BrowserURL
+}
+
+// GetDiscovery 获取题库发现方式
+func (c *Config) GetDiscovery() string {
+	c = c.top()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Discovery
+}
+
+// GetRateLimit 获取 HTTP 请求限速配置，未配置的字段使用默认值
+func (c *Config) GetRateLimit() RateLimit {
+	c = c.top()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	limit := RateLimit{
+		RequestsPerSecond: defaultRequestsPerSecond,
+		Burst:             defaultBurst,
+		Concurrency:       defaultConcurrency,
+	}
+	if c.RateLimit != nil {
+		if c.RateLimit.RequestsPerSecond > 0 {
+			limit.RequestsPerSecond = c.RateLimit.RequestsPerSecond
+		} else if c.RateLimit.RequestsPerSecond < 0 {
+			limit.RequestsPerSecond = 0
+		}
+		if c.RateLimit.Burst > 0 {
+			limit.Burst = c.RateLimit.Burst
+		}
+		if c.RateLimit.Concurrency > 0 {
+			limit.Concurrency = c.RateLimit.Concurrency
+		}
+	}
+	return limit
 }
 
 // GetWebPassword 获取 Web 访问密码哈希
 func (c *Config) GetWebPassword() string {
+	c = c.top()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.WebPassword
@@ -531,6 +1063,7 @@ func (c *Config) GetWebPassword() string {
 
 // SetWebPassword 设置 Web 访问密码（存储哈希值）
 func (c *Config) SetWebPassword(password string) error {
+	c = c.top()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if password == "" {
@@ -547,21 +1080,24 @@ func (c *Config) SetWebPassword(password string) error {
 
 // VerifyWebPassword 验证 Web 访问密码
 func (c *Config) VerifyWebPassword(password string) bool {
+	c = c.top()
 	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if c.WebPassword == "" {
+	hash := c.WebPassword
+	c.mu.RUnlock()
+	if hash == "" {
 		return true
 	}
-	// 检查是否是 bcrypt 格式（以 $2 开头）
-	if len(c.WebPassword) > 3 && c.WebPassword[:2] == "$2" {
-		err := bcrypt.CompareHashAndPassword([]byte(c.WebPassword), []byte(password))
-		return err == nil
-	}
-	// 兼容旧的 SHA256 格式（64位十六进制）
-	// 验证通过后会在下次设置时自动迁移到 bcrypt
-	if len(c.WebPassword) == 64 {
+
+	// 迁移标记的旧版 SHA256 哈希，验证通过后改为 bcrypt 保存
+	if legacy, ok := strings.CutPrefix(hash, legacyPasswordPrefix); ok {
 		h := sha256.Sum256([]byte("mosoteach_pwd_" + password))
-		return c.WebPassword == hex.EncodeToString(h[:])
+		if legacy != hex.EncodeToString(h[:]) {
+			return false
+		}
+		if err := c.SetWebPassword(password); err != nil {
+			slog.Warn("更新 Web 访问密码哈希失败", "error", err)
+		}
+		return true
 	}
-	return false
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
 }
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configBackups 保留的配置文件备份数量（user_data.json.bak.1 为最新）
+const configBackups = 5
+
+// configFileMode 配置文件权限（包含账号密码和 API Key，只允许当前用户读写）
+const configFileMode = 0600
+
+// backupPath 第 n 个备份的路径
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// readConfigFile 读取并解析配置文件，主文件存在但内容损坏时依次尝试最新的有效备份
+// 只使用 usable 返回 true（敏感字段能够解密）的备份
+// 返回实际使用的文件路径；主文件不存在时返回 os.ErrNotExist，无法读取或版本过高时直接返回错误
+func readConfigFile(path string, usable func(ConfigFile) bool) (ConfigFile, string, error) {
+	configFile, primaryErr := parseConfigFile(path)
+	if primaryErr == nil {
+		return configFile, path, nil
+	}
+	if !isCorrupt(primaryErr) {
+		return ConfigFile{}, "", primaryErr
+	}
+
+	for n := 1; n <= configBackups; n++ {
+		backup := backupPath(path, n)
+		if configFile, err := parseConfigFile(backup); err == nil && usable(configFile) {
+			return configFile, backup, nil
+		}
+	}
+	return ConfigFile{}, "", primaryErr
+}
+
+// isCorrupt 是否为文件内容无法解析的错误
+func isCorrupt(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// parseConfigFile 读取并解析单个配置文件
+func parseConfigFile(path string) (ConfigFile, error) {
+	var configFile ConfigFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return configFile, err
+	}
+	// 旧版备份在内存中升级到当前结构
+	if data, err = upgradeConfig(data); err != nil {
+		return configFile, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &configFile); err != nil {
+		return configFile, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return configFile, nil
+}
+
+// writeConfigFile 写入配置文件：内容有变化时先轮换备份，再原子替换主文件
+func writeConfigFile(path string, data []byte) error {
+	current, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+	// 只备份有效且敏感字段已加密的内容，避免明文密码留在备份中
+	if err == nil && isBackupable(current) {
+		if err := rotateBackups(path, current); err != nil {
+			return fmt.Errorf("备份配置文件失败: %w", err)
+		}
+	}
+	return writeFileAtomic(path, data, configFileMode)
+}
+
+// isBackupable 内容是否可以作为备份保存
+func isBackupable(data []byte) bool {
+	var configFile ConfigFile
+	if err := json.Unmarshal(data, &configFile); err != nil {
+		return false
+	}
+	return !hasPlaintextSecrets(configFile)
+}
+
+// removePlaintextBackups 删除包含明文敏感字段的旧备份
+func removePlaintextBackups(path string) {
+	for n := 1; n <= configBackups; n++ {
+		backup := backupPath(path, n)
+		if configFile, err := parseConfigFile(backup); err == nil && hasPlaintextSecrets(configFile) {
+			os.Remove(backup)
+		}
+	}
+}
+
+// rotateBackups 将已有备份依次后移，并把当前内容保存为最新的备份
+func rotateBackups(path string, current []byte) error {
+	for n := configBackups - 1; n >= 1; n-- {
+		if err := os.Rename(backupPath(path, n), backupPath(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeFileAtomic(backupPath(path, 1), current, configFileMode)
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并同步到磁盘，再重命名替换目标文件
+// 写入过程中崩溃或磁盘已满时原文件保持不变
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// 同步目录，确保重命名已落盘（部分平台不支持，忽略错误）
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
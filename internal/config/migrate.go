@@ -0,0 +1,270 @@
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"mosoteach/internal/state"
+)
+
+// currentSchemaVersion 当前配置文件结构版本，等于最后一个迁移的版本号
+const currentSchemaVersion = 3
+
+// legacyPasswordPrefix 旧版 SHA256 Web 访问密码哈希的格式前缀（验证成功后自动改为 bcrypt）
+const legacyPasswordPrefix = "sha256:"
+
+// migration 配置文件结构升级，将 version-1 版本的配置升级到 version 版本
+// 在 JSON 层面操作，旧版结构中的字段可能已不在 ConfigFile 中
+// 从配置文件移到状态文件的数据放入 moved，写入升级后的配置文件之前先导入状态文件
+type migration struct {
+	version int
+	name    string
+	apply   func(doc map[string]any, moved movedState) error
+}
+
+// movedState 迁移时从配置文件移出的运行状态，按账号ID分组
+type movedState map[string]*state.Legacy
+
+// account 账号移出的运行状态
+func (m movedState) account(id string) *state.Legacy {
+	if m[id] == nil {
+		m[id] = &state.Legacy{}
+	}
+	return m[id]
+}
+
+// migrations 按版本顺序注册的迁移，修改配置文件结构时在末尾追加并更新 currentSchemaVersion
+var migrations = []migration{
+	{version: 1, name: "标记旧版 SHA256 Web 访问密码", apply: migrateWebPasswordSHA256},
+	{version: 2, name: "课程列表移到状态文件", apply: migrateDiscoveredCourses},
+	{version: 3, name: "登录会话、题库缓存和完成记录移到状态文件", apply: migrateRuntimeState},
+}
+
+// migrateWebPasswordSHA256 旧版 Web 访问密码为 64 位十六进制的 SHA256 哈希，加上格式前缀以便和 bcrypt 哈希区分
+func migrateWebPasswordSHA256(doc map[string]any, _ movedState) error {
+	hash, _ := doc["web_password"].(string)
+	if len(hash) != 64 {
+		return nil
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return nil
+	}
+	doc["web_password"] = legacyPasswordPrefix + hash
+	return nil
+}
+
+// migrateDiscoveredCourses 课程的名称、状态和刷新时间移到各账号的状态文件，配置文件中只保留筛选和置顶设置
+func migrateDiscoveredCourses(doc map[string]any, moved movedState) error {
+	splitCourses(doc, moved.account(DefaultAccount))
+	accounts, _ := doc["accounts"].([]any)
+	for _, item := range accounts {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, _ := entry["id"].(string); id != "" {
+			splitCourses(entry, moved.account(id))
+		}
+	}
+	return nil
+}
+
+// splitCourses 将 courses 中的发现信息移到 legacy，只保留设置过筛选或置顶的课程
+func splitCourses(doc map[string]any, legacy *state.Legacy) {
+	list, ok := doc["courses"].([]any)
+	if !ok {
+		return
+	}
+
+	var settings []any
+	for _, item := range list {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		var course state.Course
+		if data, err := json.Marshal(entry); err == nil && json.Unmarshal(data, &course) == nil && course.ID != "" {
+			legacy.Courses = append(legacy.Courses, course)
+		}
+
+		setting := map[string]any{"id": entry["id"]}
+		if filter, _ := entry["filter"].(string); filter != "" {
+			setting["filter"] = filter
+		}
+		if pinned, _ := entry["pinned"].(bool); pinned {
+			setting["pinned"] = true
+		}
+		if len(setting) > 1 {
+			settings = append(settings, setting)
+		}
+	}
+
+	if len(settings) == 0 {
+		delete(doc, "courses")
+	} else {
+		doc["courses"] = settings
+	}
+}
+
+// migrateRuntimeState 旧版配置文件中的登录会话、题库缓存、完成记录和题库移到状态文件
+// 登录会话可能已加密，导入状态文件时再解密
+func migrateRuntimeState(doc map[string]any, moved movedState) error {
+	var old struct {
+		CachedQuizzes []state.CachedQuiz           `json:"cached_quizzes"`
+		CourseCache   map[string]state.CourseCache `json:"course_cache"`
+		Completed     []state.CompletionRecord     `json:"completed_quizzes"`
+		CompletedURLs []string                     `json:"completed_urls"`
+		QuestionBank  map[string]string            `json:"question_bank"`
+	}
+	if err := decodeDoc(doc, &old); err != nil {
+		return err
+	}
+	legacy := moved.account(DefaultAccount)
+	legacy.CachedQuizzes = old.CachedQuizzes
+	legacy.CourseCache = old.CourseCache
+	legacy.Completed = old.Completed
+	legacy.CompletedURLs = old.CompletedURLs
+	legacy.QuestionBank = old.QuestionBank
+	for _, key := range []string{"cached_quizzes", "course_cache", "completed_quizzes", "completed_urls", "question_bank"} {
+		delete(doc, key)
+	}
+	if err := moveSession(doc, legacy); err != nil {
+		return err
+	}
+
+	accounts, _ := doc["accounts"].([]any)
+	for _, item := range accounts {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, _ := entry["id"].(string); id != "" {
+			if err := moveSession(entry, moved.account(id)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// moveSession 将 user_data 中的登录会话移到 legacy
+func moveSession(doc map[string]any, legacy *state.Legacy) error {
+	userData, ok := doc["user_data"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	var old struct {
+		Cookie  string              `json:"Cookie"`
+		Cookies []state.SavedCookie `json:"cookies"`
+	}
+	if err := decodeDoc(userData, &old); err != nil {
+		return err
+	}
+	legacy.Cookie = old.Cookie
+	legacy.Cookies = old.Cookies
+	delete(userData, "Cookie")
+	delete(userData, "cookies")
+	return nil
+}
+
+// decodeDoc 将 JSON 层面的配置解析为结构体
+func decodeDoc(doc map[string]any, v any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// schemaVersionOf 读取配置的结构版本，没有版本字段的旧版配置为 0
+func schemaVersionOf(doc map[string]any) int {
+	version, _ := doc["schema_version"].(float64)
+	return int(version)
+}
+
+// pendingMigrations 解析配置并返回需要执行的迁移
+func pendingMigrations(data []byte) (map[string]any, []migration, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	version := schemaVersionOf(doc)
+	if version > currentSchemaVersion {
+		return nil, nil, fmt.Errorf("配置文件版本 %d 高于当前程序支持的版本 %d，请升级程序", version, currentSchemaVersion)
+	}
+
+	var steps []migration
+	for _, step := range migrations {
+		if step.version > version {
+			steps = append(steps, step)
+		}
+	}
+	return doc, steps, nil
+}
+
+// run 执行迁移并返回升级后的内容
+func (m migration) run(doc map[string]any, moved movedState) ([]byte, error) {
+	if err := m.apply(doc, moved); err != nil {
+		return nil, fmt.Errorf("升级配置到版本 %d（%s）失败: %w", m.version, m.name, err)
+	}
+	doc["schema_version"] = m.version
+	return json.MarshalIndent(doc, "", "    ")
+}
+
+// upgradeConfig 在内存中将配置升级到当前版本（用于从旧版备份恢复），无需升级时原样返回
+// 只读取配置，移出的运行状态不会导入状态文件
+func upgradeConfig(data []byte) ([]byte, error) {
+	doc, steps, err := pendingMigrations(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		if data, err = step.run(doc, movedState{}); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// upgradeConfigFile 将磁盘上的旧版配置文件逐步升级到当前版本，每一步之前先把当前内容保存为最新的备份
+// 迁移移出的运行状态先通过 importState 导入状态文件再写入配置文件，导入失败时配置文件保持不变
+// 文件不存在或无法解析时不做处理，交给 readConfigFile 从备份恢复
+func upgradeConfigFile(path string, importState func(movedState) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	if !json.Valid(data) {
+		return nil
+	}
+	doc, steps, err := pendingMigrations(data)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		moved := movedState{}
+		next, err := step.run(doc, moved)
+		if err != nil {
+			return err
+		}
+		if len(moved) > 0 {
+			if err := importState(moved); err != nil {
+				return fmt.Errorf("升级配置到版本 %d（%s）时保存运行状态失败: %w", step.version, step.name, err)
+			}
+		}
+		if err := rotateBackups(path, data); err != nil {
+			return fmt.Errorf("备份配置文件失败: %w", err)
+		}
+		data = next
+		if err := writeFileAtomic(path, data, configFileMode); err != nil {
+			return err
+		}
+		slog.Info("已升级配置文件", "file", path, "from", step.version-1, "to", step.version, "migration", step.name)
+	}
+	return nil
+}
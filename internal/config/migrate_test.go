@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUpgradeConfig(t *testing.T) {
+	const sha = "5e884898da28047151d0e56f8dc6292773603d0d181d8d2a62d21b07f5b3f3bd"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]any // 升级后需要检查的字段，nil 表示字段不存在
+		wantErr string
+	}{
+		{
+			name:  "旧版 SHA256 Web 密码加上前缀",
+			input: `{"web_password": "` + sha + `"}`,
+			want: map[string]any{
+				"schema_version": float64(currentSchemaVersion),
+				"web_password":   legacyPasswordPrefix + sha,
+			},
+		},
+		{
+			name:  "bcrypt 密码保持不变",
+			input: `{"web_password": "$2a$10$abcdefghijklmnopqrstuv"}`,
+			want:  map[string]any{"web_password": "$2a$10$abcdefghijklmnopqrstuv"},
+		},
+		{
+			name:  "课程只保留筛选和置顶设置",
+			input: `{"courses": [{"id": "c1", "name": "课程1", "filter": "open", "pinned": true}, {"id": "c2", "name": "课程2"}]}`,
+			want: map[string]any{
+				"courses": []any{map[string]any{"id": "c1", "filter": "open", "pinned": true}},
+			},
+		},
+		{
+			name:  "没有设置的课程列表删除",
+			input: `{"courses": [{"id": "c1", "name": "课程1"}], "accounts": [{"id": "a", "courses": [{"id": "c2", "name": "课程2", "pinned": true}]}]}`,
+			want: map[string]any{
+				"courses":  nil,
+				"accounts": []any{map[string]any{"id": "a", "courses": []any{map[string]any{"id": "c2", "pinned": true}}}},
+			},
+		},
+		{
+			name:  "运行状态从配置文件中移除",
+			input: `{"user_data": {"user_name": "u", "Cookie": "a=1", "cookies": [{"name": "a", "value": "1"}]}, "cached_quizzes": [{"course_id": "c"}], "completed_urls": ["https://example.com"], "question_bank": {"k": "A"}, "accounts": [{"id": "a", "user_data": {"Cookie": "b=2"}}]}`,
+			want: map[string]any{
+				"user_data":      map[string]any{"user_name": "u"},
+				"cached_quizzes": nil,
+				"completed_urls": nil,
+				"question_bank":  nil,
+				"accounts":       []any{map[string]any{"id": "a", "user_data": map[string]any{}}},
+			},
+		},
+		{
+			name:  "当前版本原样返回",
+			input: `{"schema_version": ` + strconv.Itoa(currentSchemaVersion) + `, "web_password": "` + sha + `"}`,
+			want:  map[string]any{"web_password": sha},
+		},
+		{
+			name:    "版本高于当前程序",
+			input:   `{"schema_version": 99}`,
+			wantErr: "高于当前程序支持的版本",
+		},
+		{
+			name:    "无法解析",
+			input:   `{`,
+			wantErr: "unexpected end of JSON input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := upgradeConfig([]byte(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc map[string]any
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+			for field, want := range tt.want {
+				if got := doc[field]; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", field, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadMovesRuntimeState(t *testing.T) {
+	t.Setenv(envPassphrase, "")
+	t.Setenv(envSecretKey, "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "user_data.json")
+	legacy := `{
+		"user_data": {"user_name": "user", "password": "secret", "Cookie": "session=1"},
+		"question_bank": {"q1": "A"},
+		"completed_quizzes": [{"course_id": "c1", "quiz_id": "q1"}]
+	}`
+	if err := os.WriteFile(path, []byte(legacy), configFileMode); err != nil {
+		t.Fatal(err)
+	}
+
+	c := loadTestConfig(t, dir, ConfigFile{}, "")
+	if got := c.GetCookie(); got != "session=1" {
+		t.Errorf("GetCookie() = %q, want %q", got, "session=1")
+	}
+	if answer, ok := c.State().LookupAnswer("q1"); !ok || answer.Answer != "A" {
+		t.Errorf("LookupAnswer(q1) = %+v, %v", answer, ok)
+	}
+	if !c.IsQuizCompleted("c1", "q1", "") {
+		t.Error("完成记录没有迁移")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"question_bank", "completed_quizzes"} {
+		if _, ok := doc[key]; ok {
+			t.Errorf("配置文件中仍有 %s", key)
+		}
+	}
+	if _, ok := doc["user_data"].(map[string]any)["Cookie"]; ok {
+		t.Error("配置文件中仍有登录会话")
+	}
+	if schemaVersionOf(doc) != currentSchemaVersion {
+		t.Errorf("schema_version = %d, want %d", schemaVersionOf(doc), currentSchemaVersion)
+	}
+}
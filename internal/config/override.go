@@ -0,0 +1,449 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"mosoteach/internal/state"
+)
+
+// 配置值的来源，按优先级从低到高：内置默认值 < 配置文件 < 环境变量 < 命令行参数
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// envPrefix 配置项对应的环境变量前缀，如 models.0.api_key → MOSO_MODELS_0_API_KEY
+const envPrefix = "MOSO_"
+
+// defaultListen 默认监听地址
+const defaultListen = ":11451"
+
+// setting 可通过环境变量和命令行参数覆盖的配置项
+type setting struct {
+	key     string                          // 配置项名称，如 user_name、models.0.api_key
+	aliases []string                        // 额外的环境变量名
+	field   func(f *ConfigFile) any         // 返回字段指针（*string / *int / *bool / *float64）
+	parse   func(value string) (any, error) // 可选：自定义解析
+}
+
+// env 配置项对应的环境变量名
+func (s setting) env() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+// get 读取字段值（不修改 f）
+func (s setting) get(f ConfigFile) any {
+	f = cloneConfigFile(f)
+	switch p := s.field(&f).(type) {
+	case *string:
+		return *p
+	case *int:
+		return *p
+	case *bool:
+		return *p
+	case *float64:
+		return *p
+	}
+	return nil
+}
+
+// set 解析字符串并写入字段
+func (s setting) set(f *ConfigFile, value string) error {
+	if s.parse != nil {
+		parsed, err := s.parse(value)
+		if err != nil {
+			return err
+		}
+		return s.assign(f, parsed)
+	}
+
+	switch p := s.field(f).(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("应为整数: %q", value)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("应为 true 或 false: %q", value)
+		}
+		*p = b
+	case *float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("应为数字: %q", value)
+		}
+		*p = n
+	}
+	return nil
+}
+
+// assign 直接写入字段值
+func (s setting) assign(f *ConfigFile, value any) error {
+	switch p := s.field(f).(type) {
+	case *string:
+		*p = value.(string)
+	case *int:
+		*p = value.(int)
+	case *bool:
+		*p = value.(bool)
+	case *float64:
+		*p = value.(float64)
+	}
+	return nil
+}
+
+// rateLimitField 限速配置字段，未配置时创建
+func rateLimitField(f *ConfigFile) *RateLimit {
+	if f.RateLimit == nil {
+		f.RateLimit = &RateLimit{}
+	}
+	return f.RateLimit
+}
+
+// webPasswordHashes 明文 Web 访问密码 → 本进程中计算的哈希
+// bcrypt 每次使用新的盐，重新加载配置时复用同一个哈希，否则每次都会被当作密码已修改
+var webPasswordHashes sync.Map
+
+// hashWebPassword Web 访问密码可以直接写 bcrypt 哈希，否则按明文处理并计算哈希（同一明文在本进程中只计算一次）
+func hashWebPassword(value string) (any, error) {
+	if value == "" || strings.HasPrefix(value, "$2") {
+		return value, nil
+	}
+	if hashed, ok := webPasswordHashes.Load(value); ok {
+		return hashed, nil
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(value), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := webPasswordHashes.LoadOrStore(value, string(hashed))
+	return actual, nil
+}
+
+// settingsFor 列出所有可覆盖的配置项，模型配置按 f 中已有的模型展开
+func settingsFor(f ConfigFile) []setting {
+	list := []setting{
+		{key: "user_name", aliases: []string{"MOSO_USER"}, field: func(f *ConfigFile) any { return &f.UserData.UserName }},
+		{key: "password", field: func(f *ConfigFile) any { return &f.UserData.Password }},
+		{key: "listen", field: func(f *ConfigFile) any { return &f.Listen }},
+		{key: "debug", field: func(f *ConfigFile) any { return &f.Debug }},
+		{key: "submit_delay", field: func(f *ConfigFile) any { return &f.SubmitDelay }},
+		{key: "web_password", field: func(f *ConfigFile) any { return &f.WebPassword }, parse: hashWebPassword},
+		{key: "chrome_path", field: func(f *ConfigFile) any { return &f.ChromePath }},
+		{key: "browser_url", field: func(f *ConfigFile) any { return &f.BrowserURL }},
+		{key: "discovery", field: func(f *ConfigFile) any { return &f.Discovery }},
+		{key: "rate_limit.requests_per_second", field: func(f *ConfigFile) any { return &rateLimitField(f).RequestsPerSecond }},
+		{key: "rate_limit.burst", field: func(f *ConfigFile) any { return &rateLimitField(f).Burst }},
+		{key: "rate_limit.concurrency", field: func(f *ConfigFile) any { return &rateLimitField(f).Concurrency }},
+	}
+	for i := range f.Models {
+		for _, mf := range modelFields {
+			list = append(list, setting{
+				key:   fmt.Sprintf("models.%d.%s", i, mf.name),
+				field: func(f *ConfigFile) any { return mf.field(&f.Models[i]) },
+			})
+		}
+	}
+	return list
+}
+
+// modelFields 每个模型可覆盖的字段，环境变量按模型在配置文件中的序号指定，如 MOSO_MODELS_0_API_KEY
+var modelFields = []struct {
+	name  string
+	field func(m *ModelConfig) any
+}{
+	{"name", func(m *ModelConfig) any { return &m.Name }},
+	{"enabled", func(m *ModelConfig) any { return &m.Enabled }},
+	{"base_url", func(m *ModelConfig) any { return &m.BaseURL }},
+	{"api_key", func(m *ModelConfig) any { return &m.APIKey }},
+	{"model", func(m *ModelConfig) any { return &m.Model }},
+	{"max_tokens", func(m *ModelConfig) any { return &m.MaxTokens }},
+	{"timeout", func(m *ModelConfig) any { return &m.Timeout }},
+	{"proxy", func(m *ModelConfig) any { return &m.Proxy }},
+}
+
+// modelOverride 被环境变量或命令行参数覆盖的模型，按生效的模型名称记录（界面中可以调整模型顺序）
+type modelOverride struct {
+	fields map[string]string // 被覆盖的字段 → 来源
+	file   ModelConfig       // 配置文件中的值
+}
+
+// modelOverridesOf 按加载时的序号找出被覆盖的模型，改为按名称记录
+func modelOverridesOf(effective, fileLayer ConfigFile, sources map[string]string) map[string]modelOverride {
+	overrides := make(map[string]modelOverride)
+	for i, m := range effective.Models {
+		if i >= len(fileLayer.Models) {
+			break
+		}
+		for _, mf := range modelFields {
+			source := sources[fmt.Sprintf("models.%d.%s", i, mf.name)]
+			if source != SourceEnv && source != SourceFlag {
+				continue
+			}
+			o, ok := overrides[m.Name]
+			if !ok {
+				o = modelOverride{fields: make(map[string]string), file: fileLayer.Models[i]}
+				overrides[m.Name] = o
+			}
+			o.fields[mf.name] = source
+		}
+	}
+	return overrides
+}
+
+// copyField 将 src 指向的字段值复制到 dst（同类型的字段指针）
+func copyField(dst, src any) {
+	switch p := dst.(type) {
+	case *string:
+		*p = *src.(*string)
+	case *int:
+		*p = *src.(*int)
+	case *bool:
+		*p = *src.(*bool)
+	case *float64:
+		*p = *src.(*float64)
+	}
+}
+
+// cloneConfigFile 复制配置中会被覆盖修改的部分，避免修改共享的切片和指针
+func cloneConfigFile(f ConfigFile) ConfigFile {
+	f.Models = cloneModels(f.Models)
+	f.Accounts = cloneAccounts(f.Accounts)
+	if f.RateLimit != nil {
+		limit := *f.RateLimit
+		f.RateLimit = &limit
+	}
+	return f
+}
+
+// SetFilePath 设置配置文件路径，状态文件和密钥文件放在同一目录，需在 Load 之前调用
+func (c *Config) SetFilePath(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dir := filepath.Dir(path)
+	c.FilePath = path
+	c.StatePath = filepath.Join(dir, "state.json")
+	c.KeyPath = strings.TrimSuffix(path, filepath.Ext(path)) + ".key"
+	c.state = state.NewFileStore(c.StatePath)
+}
+
+// SetFlagOverrides 设置命令行参数指定的配置值（配置项名称 → 值），需在 Load 之前调用
+func (c *Config) SetFlagOverrides(values map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flags = maps.Clone(values)
+}
+
+// applyOverrides 在配置文件的值之上依次应用环境变量和命令行参数，返回每项的来源（调用方持有锁）
+// fromFile 表示配置文件是否存在，fileModels 表示模型列表是否来自配置文件
+func (c *Config) applyOverrides(configFile *ConfigFile, fromFile, fileModels bool) (map[string]string, error) {
+	settings := settingsFor(*configFile)
+	sources := make(map[string]string, len(settings))
+	known := make(map[string]bool, len(settings))
+	defaults := ConfigFile{Models: getDefaultModels()}
+
+	for _, s := range settings {
+		known[s.key] = true
+
+		value := s.get(*configFile)
+		source := SourceDefault
+		switch {
+		case !fromFile:
+		case strings.HasPrefix(s.key, "models."):
+			if fileModels {
+				source = SourceFile
+			}
+		case value != s.get(defaults):
+			source = SourceFile
+		}
+
+		for _, name := range append([]string{s.env()}, s.aliases...) {
+			if env := os.Getenv(name); env != "" {
+				if err := s.set(configFile, env); err != nil {
+					return nil, fmt.Errorf("环境变量 %s: %w", name, err)
+				}
+				source = SourceEnv
+				break
+			}
+		}
+		if flag, ok := c.flags[s.key]; ok {
+			if err := s.set(configFile, flag); err != nil {
+				return nil, fmt.Errorf("参数 %s: %w", s.key, err)
+			}
+			source = SourceFlag
+		}
+		sources[s.key] = source
+	}
+
+	for key := range c.flags {
+		if !known[key] {
+			return nil, fmt.Errorf("未知的配置项: %s", key)
+		}
+	}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, envPrefix+"MODELS_") && !knownEnv(settings, name) {
+			slog.Warn("忽略环境变量：只能覆盖配置文件中已有的模型", "name", name)
+		}
+	}
+
+	return sources, nil
+}
+
+// knownEnv 环境变量是否对应某个配置项
+func knownEnv(settings []setting, name string) bool {
+	for _, s := range settings {
+		if s.env() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreOverridden 保存前将来自环境变量和命令行参数的值还原为配置文件中的值，
+// 覆盖值只在本次运行中生效，不会写入配置文件（调用方持有锁）
+// 模型按名称对应：界面中调整顺序后仍还原到同一个模型，已删除或改名的模型不再还原
+func (c *Config) restoreOverridden(configFile *ConfigFile) {
+	*configFile = cloneConfigFile(*configFile)
+	for _, s := range settingsFor(*configFile) {
+		if strings.HasPrefix(s.key, "models.") {
+			continue
+		}
+		source := c.sources[s.key]
+		if source != SourceEnv && source != SourceFlag {
+			continue
+		}
+		s.assign(configFile, s.get(c.fileLayer))
+	}
+	if c.fileLayer.RateLimit == nil && configFile.RateLimit != nil && *configFile.RateLimit == (RateLimit{}) {
+		configFile.RateLimit = nil
+	}
+
+	for i := range configFile.Models {
+		o, ok := c.modelOverrides[configFile.Models[i].Name]
+		if !ok {
+			continue
+		}
+		for _, mf := range modelFields {
+			if o.fields[mf.name] != "" {
+				copyField(mf.field(&configFile.Models[i]), mf.field(&o.file))
+			}
+		}
+	}
+}
+
+// replaceFileLayer 替换配置文件中的值，被覆盖的配置项保留当前的覆盖值，返回新的生效配置（调用方持有锁）
+func (c *Config) replaceFileLayer(fileLayer ConfigFile) ConfigFile {
+	effective := cloneConfigFile(fileLayer)
+	current := c.current()
+	for _, s := range settingsFor(effective) {
+		if strings.HasPrefix(s.key, "models.") {
+			continue
+		}
+		if source := c.sources[s.key]; source == SourceEnv || source == SourceFlag {
+			s.assign(&effective, s.get(current))
+		}
+	}
+
+	// 被覆盖的模型按配置文件中的名称找到新的值，按生效的名称找到覆盖值
+	for name, o := range c.modelOverrides {
+		i := slices.IndexFunc(effective.Models, func(m ModelConfig) bool { return m.Name == o.file.Name })
+		j := slices.IndexFunc(current.Models, func(m ModelConfig) bool { return m.Name == name })
+		if i < 0 || j < 0 {
+			continue
+		}
+		o.file = cloneModels(fileLayer.Models[i : i+1])[0]
+		c.modelOverrides[name] = o
+		for _, mf := range modelFields {
+			if o.fields[mf.name] != "" {
+				copyField(mf.field(&effective.Models[i]), mf.field(&current.Models[j]))
+			}
+		}
+	}
+
+	c.fileLayer = cloneConfigFile(fileLayer)
+	return effective
+}
+
+// overriddenFields 被覆盖的配置项在 diffConfig 中的字段路径 → 来源（调用方持有锁）
+func (c *Config) overriddenFields() map[string]string {
+	fields := make(map[string]string)
+	for key, source := range c.sources {
+		if (source != SourceEnv && source != SourceFlag) || strings.HasPrefix(key, "models.") {
+			continue
+		}
+		if key == "user_name" || key == "password" {
+			key = "user_data." + key
+		}
+		fields[key] = source
+	}
+	for _, o := range c.modelOverrides {
+		for field, source := range o.fields {
+			fields["models["+o.file.Name+"]."+field] = source
+		}
+	}
+	return fields
+}
+
+// updateModelSources 模型列表修改后按名称重新计算模型各字段的来源（调用方持有锁）
+func (c *Config) updateModelSources() {
+	sources := make(map[string]string, len(c.sources))
+	for key, source := range c.sources {
+		if !strings.HasPrefix(key, "models.") {
+			sources[key] = source
+		}
+	}
+	for i, m := range c.Models {
+		o := c.modelOverrides[m.Name]
+		for _, mf := range modelFields {
+			source := SourceFile
+			if o.fields[mf.name] != "" {
+				source = o.fields[mf.name]
+			}
+			sources[fmt.Sprintf("models.%d.%s", i, mf.name)] = source
+		}
+	}
+	c.sources = sources
+}
+
+// GetSources 获取每个配置项当前生效值的来源
+func (c *Config) GetSources() map[string]string {
+	c = c.top()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.sources)
+}
+
+// GetListen 获取 Web 服务监听地址，只写端口时监听所有地址
+func (c *Config) GetListen() string {
+	c = c.top()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	listen := c.Listen
+	if listen == "" {
+		return defaultListen
+	}
+	if _, err := strconv.Atoi(listen); err == nil {
+		return ":" + listen
+	}
+	return listen
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	file := ConfigFile{
+		UserData:    UserData{UserName: "file-user"},
+		SubmitDelay: 3,
+		Models:      []ModelConfig{{Name: "A", APIKey: "file-key"}},
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		flags    map[string]string
+		fromFile bool
+		check    func(t *testing.T, f ConfigFile)
+		sources  map[string]string
+		wantErr  string
+	}{
+		{
+			name:     "只有配置文件",
+			fromFile: true,
+			check: func(t *testing.T, f ConfigFile) {
+				if f.UserData.UserName != "file-user" || f.SubmitDelay != 3 {
+					t.Errorf("got %+v", f)
+				}
+			},
+			sources: map[string]string{"user_name": SourceFile, "submit_delay": SourceFile, "listen": SourceDefault, "models.0.api_key": SourceFile},
+		},
+		{
+			name:     "配置文件不存在",
+			sources:  map[string]string{"user_name": SourceDefault, "models.0.api_key": SourceDefault},
+			fromFile: false,
+		},
+		{
+			name:     "环境变量覆盖配置文件",
+			env:      map[string]string{"MOSO_SUBMIT_DELAY": "7", "MOSO_USER": "env-user", "MOSO_MODELS_0_API_KEY": "env-key"},
+			fromFile: true,
+			check: func(t *testing.T, f ConfigFile) {
+				if f.UserData.UserName != "env-user" || f.SubmitDelay != 7 || f.Models[0].APIKey != "env-key" {
+					t.Errorf("got %+v", f)
+				}
+			},
+			sources: map[string]string{"user_name": SourceEnv, "submit_delay": SourceEnv, "models.0.api_key": SourceEnv},
+		},
+		{
+			name:     "命令行参数优先于环境变量",
+			env:      map[string]string{"MOSO_SUBMIT_DELAY": "7"},
+			flags:    map[string]string{"submit_delay": "9", "debug": "true"},
+			fromFile: true,
+			check: func(t *testing.T, f ConfigFile) {
+				if f.SubmitDelay != 9 || !f.Debug {
+					t.Errorf("got %+v", f)
+				}
+			},
+			sources: map[string]string{"submit_delay": SourceFlag, "debug": SourceFlag},
+		},
+		{
+			name:     "明文 Web 密码计算哈希",
+			env:      map[string]string{"MOSO_WEB_PASSWORD": "hunter2"},
+			fromFile: true,
+			check: func(t *testing.T, f ConfigFile) {
+				if bcrypt.CompareHashAndPassword([]byte(f.WebPassword), []byte("hunter2")) != nil {
+					t.Errorf("WebPassword = %q 不是 hunter2 的哈希", f.WebPassword)
+				}
+			},
+			sources: map[string]string{"web_password": SourceEnv},
+		},
+		{
+			name:     "格式错误的环境变量",
+			env:      map[string]string{"MOSO_SUBMIT_DELAY": "soon"},
+			fromFile: true,
+			wantErr:  "MOSO_SUBMIT_DELAY",
+		},
+		{
+			name:     "未知的命令行参数",
+			flags:    map[string]string{"no_such_setting": "1"},
+			fromFile: true,
+			wantErr:  "未知的配置项",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			c := &Config{flags: tt.flags}
+			configFile := cloneConfigFile(file)
+			sources, err := c.applyOverrides(&configFile, tt.fromFile, tt.fromFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.check != nil {
+				tt.check(t, configFile)
+			}
+			for key, want := range tt.sources {
+				if sources[key] != want {
+					t.Errorf("sources[%s] = %q, want %q", key, sources[key], want)
+				}
+			}
+			if file.Models[0].APIKey != "file-key" {
+				t.Error("applyOverrides 修改了共享的模型列表")
+			}
+		})
+	}
+}
+
+func TestRestoreOverridden(t *testing.T) {
+	fileLayer := ConfigFile{
+		UserData:    UserData{UserName: "file-user"},
+		SubmitDelay: 3,
+		Models:      []ModelConfig{{Name: "A", APIKey: "file-key"}, {Name: "B"}},
+	}
+
+	tests := []struct {
+		name    string
+		sources map[string]string
+		loaded  func(f *ConfigFile) // 加载时应用覆盖值后的配置，为空时与配置文件相同
+		edit    func(f *ConfigFile) // 界面中的修改（在生效配置上）
+		check   func(t *testing.T, f ConfigFile)
+	}{
+		{
+			name:    "覆盖值还原为配置文件中的值",
+			sources: map[string]string{"submit_delay": SourceEnv, "user_name": SourceFlag, "models.0.api_key": SourceEnv},
+			edit: func(f *ConfigFile) {
+				f.SubmitDelay = 7
+				f.UserData.UserName = "flag-user"
+				f.Models[0].APIKey = "env-key"
+			},
+			check: func(t *testing.T, f ConfigFile) {
+				if f.SubmitDelay != 3 || f.UserData.UserName != "file-user" || f.Models[0].APIKey != "file-key" {
+					t.Errorf("got %+v", f)
+				}
+			},
+		},
+		{
+			name:    "未覆盖的修改保留",
+			sources: map[string]string{"submit_delay": SourceEnv, "debug": SourceFile},
+			edit: func(f *ConfigFile) {
+				f.SubmitDelay = 7
+				f.Debug = true
+				f.Models[1].APIKey = "new-key"
+			},
+			check: func(t *testing.T, f ConfigFile) {
+				if f.SubmitDelay != 3 || !f.Debug || f.Models[1].APIKey != "new-key" {
+					t.Errorf("got %+v", f)
+				}
+			},
+		},
+		{
+			name:    "模型调整顺序后按名称还原",
+			sources: map[string]string{"models.0.api_key": SourceEnv},
+			edit: func(f *ConfigFile) {
+				f.Models[0].APIKey = "env-key"
+				f.Models[0], f.Models[1] = f.Models[1], f.Models[0]
+			},
+			check: func(t *testing.T, f ConfigFile) {
+				if f.Models[0].Name != "B" || f.Models[0].APIKey != "" || f.Models[1].APIKey != "file-key" {
+					t.Errorf("got %+v", f.Models)
+				}
+			},
+		},
+		{
+			name:    "添加模型后仍还原被覆盖的模型",
+			sources: map[string]string{"models.1.base_url": SourceFlag},
+			edit: func(f *ConfigFile) {
+				f.Models[1].BaseURL = "https://flag.example.com"
+				f.Models = append([]ModelConfig{{Name: "C"}}, f.Models...)
+			},
+			check: func(t *testing.T, f ConfigFile) {
+				if len(f.Models) != 3 || f.Models[2].Name != "B" || f.Models[2].BaseURL != "" {
+					t.Errorf("got %+v", f.Models)
+				}
+			},
+		},
+		{
+			name:    "删除被覆盖的模型",
+			sources: map[string]string{"models.0.api_key": SourceEnv},
+			edit: func(f *ConfigFile) {
+				f.Models = f.Models[1:]
+			},
+			check: func(t *testing.T, f ConfigFile) {
+				if len(f.Models) != 1 || f.Models[0].Name != "B" || f.Models[0].APIKey != "" {
+					t.Errorf("got %+v", f.Models)
+				}
+			},
+		},
+		{
+			name:    "模型名称被覆盖",
+			sources: map[string]string{"models.0.name": SourceEnv, "models.0.api_key": SourceEnv},
+			loaded: func(f *ConfigFile) {
+				f.Models[0].Name = "env-name"
+				f.Models[0].APIKey = "env-key"
+			},
+			edit: func(f *ConfigFile) {
+				f.Models[1].Enabled = true
+			},
+			check: func(t *testing.T, f ConfigFile) {
+				if f.Models[0].Name != "A" || f.Models[0].APIKey != "file-key" {
+					t.Errorf("got %+v", f.Models)
+				}
+			},
+		},
+		{
+			name:    "覆盖限速时不写入空的限速配置",
+			sources: map[string]string{"rate_limit.burst": SourceEnv},
+			edit: func(f *ConfigFile) {
+				f.RateLimit = &RateLimit{Burst: 5}
+			},
+			check: func(t *testing.T, f ConfigFile) {
+				if f.RateLimit != nil {
+					t.Errorf("RateLimit = %+v, want nil", f.RateLimit)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			effective := cloneConfigFile(fileLayer)
+			if tt.loaded != nil {
+				tt.loaded(&effective)
+			}
+			c := &Config{
+				fileLayer:      cloneConfigFile(fileLayer),
+				sources:        tt.sources,
+				modelOverrides: modelOverridesOf(effective, fileLayer, tt.sources),
+			}
+			configFile := cloneConfigFile(effective)
+			tt.edit(&configFile)
+			models := configFile.Models
+			edited := cloneModels(models)
+
+			c.restoreOverridden(&configFile)
+			tt.check(t, configFile)
+			for i := range models {
+				if models[i].Name != edited[i].Name || models[i].APIKey != edited[i].APIKey || models[i].BaseURL != edited[i].BaseURL {
+					t.Error("restoreOverridden 修改了共享的模型列表")
+				}
+			}
+		})
+	}
+}
+
+func TestHashWebPasswordStable(t *testing.T) {
+	first, err := hashWebPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := hashWebPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("同一明文密码重新加载后哈希不同，会导致所有会话失效")
+	}
+	if other, _ := hashWebPassword("other"); other == first {
+		t.Error("不同密码的哈希相同")
+	}
+}
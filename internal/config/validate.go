@@ -0,0 +1,253 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 数值配置的取值范围
+const (
+	maxSubmitDelay       = 7200 // 提交延迟最长 2 小时
+	maxRequestsPerSecond = 100
+	maxBurst             = 100
+	maxConcurrency       = 16
+	maxNameLength        = 64 // 模型名称、账号名称和用户名的最大长度
+	maxTemperature       = 2
+	maxModelTokens       = 1000000
+	maxModelTimeout      = 600 // 单次模型请求最长 10 分钟
+)
+
+// headerNamePattern 有效的 HTTP 请求头名称
+var headerNamePattern = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// ValidationErrors 保存配置时的验证错误，Web 接口以 422 返回完整列表
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, v := range e {
+		messages[i] = v.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validateConfigFile 检查配置的取值是否有效，重新加载时无效的配置不会生效，保存时无效的修改不会写入
+func validateConfigFile(f ConfigFile) []ValidationError {
+	var errs []ValidationError
+	if f.SubmitDelay < 0 || f.SubmitDelay > maxSubmitDelay {
+		errs = append(errs, ValidationError{Field: "submit_delay", Message: fmt.Sprintf("提交延迟应在 0 到 %d 秒之间", maxSubmitDelay)})
+	}
+	switch f.Discovery {
+	case "", "auto", "http", "browser":
+	default:
+		errs = append(errs, ValidationError{Field: "discovery", Message: "题库获取方式只能是 auto、http 或 browser"})
+	}
+	if f.Listen != "" {
+		if err := validateListen(f.Listen); err != nil {
+			errs = append(errs, ValidationError{Field: "listen", Message: err.Error()})
+		}
+	}
+	if f.BrowserURL != "" {
+		if err := validateURL(f.BrowserURL, "ws", "wss", "http", "https"); err != nil {
+			errs = append(errs, ValidationError{Field: "browser_url", Message: "远程浏览器地址" + err.Error()})
+		}
+	}
+	if f.RateLimit != nil {
+		errs = append(errs, validateRateLimit(*f.RateLimit)...)
+	}
+
+	errs = append(errs, validateModelList(f.Models)...)
+	errs = append(errs, validateUserData("user_data.", f.UserData)...)
+	errs = append(errs, validateCourses("courses", f.Courses)...)
+
+	seen := map[string]bool{DefaultAccount: true}
+	for i, a := range f.Accounts {
+		prefix := fmt.Sprintf("accounts[%d].", i)
+		if seen[a.ID] {
+			errs = append(errs, ValidationError{Field: prefix + "id", Message: "账号ID重复: " + a.ID})
+		}
+		seen[a.ID] = true
+		errs = append(errs, validateAccount(prefix, a)...)
+		errs = append(errs, validateCourses(prefix+"courses", a.Courses)...)
+	}
+	return errs
+}
+
+// validateAccount 检查账号的ID、名称和用户名，prefix 为字段名前缀
+func validateAccount(prefix string, a Account) []ValidationError {
+	var errs []ValidationError
+	if err := validateAccountID(a.ID); err != nil {
+		errs = append(errs, ValidationError{Field: prefix + "id", Message: err.Error()})
+	}
+	if len([]rune(a.Name)) > maxNameLength {
+		errs = append(errs, ValidationError{Field: prefix + "name", Message: fmt.Sprintf("账号名称最长 %d 个字符", maxNameLength)})
+	}
+	return append(errs, validateUserData(prefix+"user_data.", a.UserData)...)
+}
+
+// validateModelList 检查模型名称非空且不重复（保存时按名称保留原有的 API Key），Base URL 格式正确
+func validateModelList(models []ModelConfig) []ValidationError {
+	var errs []ValidationError
+	seen := make(map[string]int, len(models))
+	for i, m := range models {
+		prefix := "models[" + strconv.Itoa(i) + "]."
+		name := strings.TrimSpace(m.Name)
+		switch {
+		case name == "":
+			errs = append(errs, ValidationError{Field: prefix + "name", Message: "模型名称不能为空"})
+		case len([]rune(name)) > maxNameLength:
+			errs = append(errs, ValidationError{Field: prefix + "name", Message: fmt.Sprintf("模型名称最长 %d 个字符", maxNameLength)})
+		default:
+			if first, ok := seen[name]; ok {
+				errs = append(errs, ValidationError{
+					Field:   prefix + "name",
+					Message: fmt.Sprintf("模型名称 %s 与第 %d 个模型重复", name, first+1),
+				})
+			} else {
+				seen[name] = i
+			}
+		}
+		if m.BaseURL != "" {
+			if err := validateURL(m.BaseURL, "http", "https"); err != nil {
+				errs = append(errs, ValidationError{Field: prefix + "base_url", Message: "Base URL " + err.Error()})
+			}
+		}
+		errs = append(errs, validateModelParams(prefix, m)...)
+	}
+	return errs
+}
+
+// validateModelParams 检查模型的请求参数
+func validateModelParams(prefix string, m ModelConfig) []ValidationError {
+	var errs []ValidationError
+	if m.Temperature != nil && (*m.Temperature < 0 || *m.Temperature > maxTemperature) {
+		errs = append(errs, ValidationError{Field: prefix + "temperature", Message: fmt.Sprintf("温度应在 0 到 %d 之间", maxTemperature)})
+	}
+	if m.MaxTokens < 0 || m.MaxTokens > maxModelTokens {
+		errs = append(errs, ValidationError{Field: prefix + "max_tokens", Message: fmt.Sprintf("最大 token 数应在 0 到 %d 之间", maxModelTokens)})
+	}
+	if m.Timeout < 0 || m.Timeout > maxModelTimeout {
+		errs = append(errs, ValidationError{Field: prefix + "timeout", Message: fmt.Sprintf("超时应在 0 到 %d 秒之间", maxModelTimeout)})
+	}
+	if m.Proxy != "" {
+		if err := validateURL(m.Proxy, "http", "https", "socks5"); err != nil {
+			errs = append(errs, ValidationError{Field: prefix + "proxy", Message: "代理地址" + err.Error()})
+		}
+	}
+	for name := range m.ExtraHeaders {
+		if !headerNamePattern.MatchString(name) {
+			errs = append(errs, ValidationError{Field: prefix + "extra_headers", Message: fmt.Sprintf("请求头名称无效: %q", name)})
+		}
+	}
+	for _, key := range []string{"model", "messages"} {
+		if _, ok := m.ExtraBody[key]; ok {
+			errs = append(errs, ValidationError{Field: prefix + "extra_body", Message: key + " 由程序设置，不能在额外参数中修改"})
+		}
+	}
+	return errs
+}
+
+// validateUserData 检查账号的用户名，prefix 为字段名前缀
+func validateUserData(prefix string, u UserData) []ValidationError {
+	var errs []ValidationError
+	if u.UserName != strings.TrimSpace(u.UserName) {
+		errs = append(errs, ValidationError{Field: prefix + "user_name", Message: "用户名首尾不能有空格"})
+	} else if len([]rune(u.UserName)) > maxNameLength {
+		errs = append(errs, ValidationError{Field: prefix + "user_name", Message: fmt.Sprintf("用户名最长 %d 个字符", maxNameLength)})
+	}
+	return errs
+}
+
+// validateCourses 检查课程的筛选方式，field 为字段名
+func validateCourses(field string, courses []CourseSetting) []ValidationError {
+	var errs []ValidationError
+	for i, course := range courses {
+		switch course.Filter {
+		case CourseFilterDefault, CourseFilterInclude, CourseFilterExclude:
+		default:
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s[%d].filter", field, i),
+				Message: "课程筛选方式只能是 include、exclude 或空",
+			})
+		}
+	}
+	return errs
+}
+
+// validateRateLimit 检查限速配置的取值范围（0 使用默认值，每秒请求数为负数表示不限速）
+func validateRateLimit(limit RateLimit) []ValidationError {
+	var errs []ValidationError
+	if limit.RequestsPerSecond > maxRequestsPerSecond {
+		errs = append(errs, ValidationError{
+			Field:   "rate_limit.requests_per_second",
+			Message: fmt.Sprintf("每秒请求数不能超过 %d", maxRequestsPerSecond),
+		})
+	}
+	if limit.Burst < 0 || limit.Burst > maxBurst {
+		errs = append(errs, ValidationError{
+			Field:   "rate_limit.burst",
+			Message: fmt.Sprintf("突发请求数应在 0 到 %d 之间", maxBurst),
+		})
+	}
+	if limit.Concurrency < 0 || limit.Concurrency > maxConcurrency {
+		errs = append(errs, ValidationError{
+			Field:   "rate_limit.concurrency",
+			Message: fmt.Sprintf("并发数应在 0 到 %d 之间", maxConcurrency),
+		})
+	}
+	return errs
+}
+
+// validateURL 检查地址是否为指定协议的绝对地址
+func validateURL(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.New("格式错误")
+	}
+	if !slices.Contains(schemes, u.Scheme) {
+		return fmt.Errorf("必须以 %s:// 开头", strings.Join(schemes, "://、"))
+	}
+	if u.Host == "" {
+		return errors.New("缺少主机名")
+	}
+	return nil
+}
+
+// validateListen 检查监听地址（host:port，host 可以为空，也可以只写端口）
+func validateListen(addr string) error {
+	port := addr
+	if _, err := strconv.Atoi(addr); err != nil {
+		if _, port, err = net.SplitHostPort(addr); err != nil {
+			return errors.New("监听地址应为 host:port 格式，如 :11451 或 127.0.0.1:8080")
+		}
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return errors.New("监听端口应在 0 到 65535 之间")
+	}
+	return nil
+}
+
+// validateCurrent 验证当前生效的配置
+func (c *Config) validateCurrent() []ValidationError {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return validateConfigFile(c.current())
+}
+
+// check 验证修改后将要写入配置文件的内容，没有错误时才执行修改（调用方持有锁）
+// 来自环境变量和命令行参数的值不写入配置文件，不参与验证：无效的覆盖值只在加载时提示，不影响其他修改的保存
+func (c *Config) check(modify func(f *ConfigFile)) error {
+	next := cloneConfigFile(c.current())
+	modify(&next)
+	c.restoreOverridden(&next)
+	if errs := validateConfigFile(next); len(errs) > 0 {
+		return ValidationErrors(errs)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckIgnoresOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		save    func(c *Config) error
+		wantErr bool
+	}{
+		{
+			name: "无效的覆盖值不影响其他修改",
+			env:  map[string]string{"MOSO_SUBMIT_DELAY": "9000"},
+			save: func(c *Config) error {
+				models := c.GetModels()
+				models[0].Model = "a-2"
+				return c.UpdateModels(models)
+			},
+		},
+		{
+			name: "修改被覆盖的字段时验证写入配置文件的值",
+			env:  map[string]string{"MOSO_SUBMIT_DELAY": "9000"},
+			save: func(c *Config) error { return c.SetSubmitDelay(9000) },
+		},
+		{
+			name:    "无效的修改",
+			save:    func(c *Config) error { return c.SetSubmitDelay(9000) },
+			wantErr: true,
+		},
+		{
+			name: "无效的模型覆盖值",
+			env:  map[string]string{"MOSO_MODELS_0_BASE_URL": "not a url"},
+			save: func(c *Config) error {
+				models := c.GetModels()
+				models = append(models, ModelConfig{Name: "B", BaseURL: "https://b.example.com", Model: "b-1"})
+				return c.UpdateModels(models)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envPassphrase, "")
+			t.Setenv(envSecretKey, "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			c := loadTestConfig(t, t.TempDir(), ConfigFile{
+				SubmitDelay: 3,
+				Models:      []ModelConfig{{Name: "A", Enabled: true, BaseURL: "https://a.example.com", Model: "a-1"}},
+			}, "")
+
+			err := tt.save(c)
+			var validation ValidationErrors
+			if tt.wantErr != errors.As(err, &validation) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"time"
+)
+
+// DefaultWatchInterval 检查配置文件变化的间隔
+const DefaultWatchInterval = 2 * time.Second
+
+// Subscriber 配置变化的订阅者，previous 和 current 为重新加载前后生效的配置
+type Subscriber func(previous, current ConfigFile)
+
+// Subscribe 订阅配置变化，配置文件被修改并重新加载后调用 fn，返回取消订阅的函数
+func (c *Config) Subscribe(fn Subscriber) func() {
+	c = c.top()
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if c.subscribers == nil {
+		c.subscribers = make(map[int]Subscriber)
+	}
+	id := c.nextSubscriber
+	c.nextSubscriber++
+	c.subscribers[id] = fn
+
+	return func() {
+		c.subMu.Lock()
+		defer c.subMu.Unlock()
+		delete(c.subscribers, id)
+	}
+}
+
+// notify 通知所有订阅者（不能持有 c.mu，订阅者通常会读取配置）
+func (c *Config) notify(previous, current ConfigFile) {
+	c.subMu.Lock()
+	subscribers := make([]Subscriber, 0, len(c.subscribers))
+	for _, fn := range c.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	c.subMu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(previous, current)
+	}
+}
+
+// Reload 重新读取配置文件，校验通过后整体替换当前配置并通知订阅者
+// 配置文件无效时返回错误并继续使用当前配置；内容没有变化（如本程序自己保存）时不通知
+func (c *Config) Reload() error {
+	c = c.top()
+	c.mu.Lock()
+
+	previous := c.current()
+	err := c.reloadLocked()
+	current := c.current()
+	c.mu.Unlock()
+
+	c.refreshAccounts()
+	if !reflect.DeepEqual(previous, current) {
+		slog.Info("配置文件已修改，已重新加载", "file", c.FilePath)
+		c.notify(previous, current)
+	}
+	return err
+}
+
+// reloadLocked Reload 的实现（调用方持有锁）
+func (c *Config) reloadLocked() error {
+	if c.box == nil {
+		return fmt.Errorf("请先加载配置")
+	}
+	if err := upgradeConfigFile(c.FilePath, c.importMoved); err != nil {
+		return err
+	}
+
+	// 只读取主文件：手动修改出错时不能悄悄换成旧的备份
+	configFile, err := parseConfigFile(c.FilePath)
+	if err != nil {
+		return err
+	}
+	plaintext, err := c.box.decryptSecrets(&configFile)
+	if err != nil {
+		return fmt.Errorf("解密配置失败: %w", err)
+	}
+
+	fileModels := len(configFile.Models) > 0
+	if !fileModels {
+		configFile.Models = getDefaultModels()
+	}
+	effective := cloneConfigFile(configFile)
+	sources, err := c.applyOverrides(&effective, true, fileModels)
+	if err != nil {
+		return err
+	}
+	// 只验证配置文件中的值，无效的覆盖值已在启动时提示，不阻止重新加载
+	if errs := validateConfigFile(configFile); len(errs) > 0 {
+		return ValidationErrors(errs)
+	}
+
+	c.apply(effective, configFile, sources)
+
+	// 手动写入的明文敏感字段改为加密保存
+	if plaintext {
+		if err := c.saveInternal(); err != nil {
+			return err
+		}
+		removePlaintextBackups(c.FilePath)
+		slog.Info("已加密配置文件中的敏感字段", "key_source", c.box.source)
+	}
+	return nil
+}
+
+// Watch 定期检查配置文件，被修改后自动重新加载，直到 ctx 取消
+func (c *Config) Watch(ctx context.Context, interval time.Duration) {
+	c = c.top()
+	last, _ := os.Stat(c.FilePath)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(c.FilePath)
+		if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+			continue
+		}
+		last = info
+
+		if err := c.Reload(); err != nil {
+			slog.Warn("配置文件无效，继续使用当前配置", "file", c.FilePath, "error", err)
+		}
+	}
+}
@@ -3,11 +3,14 @@ package models
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mosoteach/internal/config"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -16,34 +19,80 @@ import (
 const (
 	systemPrompt = `你是一个专业的答题助手。请直接给出答案，不需要解释过程。对于选择题，只需要给出答案的选项字母（如A、B、C、D）。对于判断题，只需要回答"正确"或"错误"。对于填空题，直接给出答案内容。`
 
-	httpTimeout = 60 * time.Second
+	// 模型未配置请求参数时的默认值
+	defaultTemperature = 0.1
+	defaultMaxTokens   = 1000
+	defaultTimeout     = 60 * time.Second
 )
 
 var (
-	sharedHTTPClient *http.Client
-	httpClientOnce   sync.Once
+	httpClients   = make(map[string]*http.Client) // 按代理地址共享的 HTTP 客户端，键见 httpClientKey
+	httpClientsMu sync.Mutex
 )
 
-// getHTTPClient 获取共享的 HTTP 客户端
-func getHTTPClient() *http.Client {
-	httpClientOnce.Do(func() {
-		sharedHTTPClient = &http.Client{
-			Timeout: httpTimeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
+// httpClientKey 客户端缓存的键：去掉账号密码的代理地址加上完整地址的哈希，键中不保留明文密码
+func httpClientKey(proxy string) string {
+	if proxy == "" {
+		return ""
+	}
+	display := proxy
+	if u, err := url.Parse(proxy); err == nil && u.User != nil {
+		u.User = nil
+		display = u.String()
+	}
+	sum := sha256.Sum256([]byte(proxy))
+	return display + "#" + hex.EncodeToString(sum[:8])
+}
+
+// getHTTPClient 获取共享的 HTTP 客户端，proxy 为空时直接连接
+// 超时由每次请求的 context 控制，各模型的超时可以不同
+func getHTTPClient(proxy string) (*http.Client, error) {
+	key := httpClientKey(proxy)
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+
+	if client, ok := httpClients[key]; ok {
+		return client, nil
+	}
+	transport := &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("代理地址无效: %w", err)
 		}
-	})
-	return sharedHTTPClient
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	client := &http.Client{Transport: transport}
+	httpClients[key] = client
+	return client, nil
+}
+
+// pruneHTTPClients 删除配置中已没有模型使用的代理对应的客户端（如修改了代理或只用于测试连接），并关闭其空闲连接
+func pruneHTTPClients(models []config.ModelConfig) {
+	used := map[string]bool{"": true}
+	for _, m := range models {
+		used[httpClientKey(m.Proxy)] = true
+	}
+
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+	for key, client := range httpClients {
+		if !used[key] {
+			client.CloseIdleConnections()
+			delete(httpClients, key)
+		}
+	}
 }
 
 // ChatRequest OpenAI API 请求结构
 type ChatRequest struct {
 	Model       string        `json:"model"`
 	Messages    []ChatMessage `json:"messages"`
-	Temperature float64       `json:"temperature,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
 }
 
@@ -108,11 +157,11 @@ func (m *UnifiedModel) GetAnswer(ctx context.Context, question string) (string,
 				Content: fmt.Sprintf("下面是一道题目:%s", question),
 			},
 		},
-		Temperature: 0.1,
-		MaxTokens:   1000,
+		Temperature: m.temperature(),
+		MaxTokens:   m.maxTokens(),
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	jsonData, err := m.marshalRequest(reqBody)
 	if err != nil {
 		return "", fmt.Errorf("序列化请求失败: %w", err)
 	}
@@ -121,27 +170,37 @@ func (m *UnifiedModel) GetAnswer(ctx context.Context, question string) (string,
 	// 用户可以配置完整路径（如 https://api.example.com/v1/chat/completions）
 	// 或者只配置基础URL（如 https://api.deepseek.com），代码会自动补全
 	baseURL := strings.TrimSuffix(m.cfg.BaseURL, "/")
-	var url string
+	var endpoint string
 	if strings.HasSuffix(baseURL, "/chat/completions") {
 		// 用户已配置完整路径
-		url = baseURL
+		endpoint = baseURL
 	} else if strings.Contains(baseURL, "/v1") || strings.Contains(baseURL, "/v1beta") {
 		// URL已包含版本路径，只需添加 /chat/completions
-		url = baseURL + "/chat/completions"
+		endpoint = baseURL + "/chat/completions"
 	} else {
 		// 添加默认的 /v1/chat/completions
-		url = baseURL + "/v1/chat/completions"
+		endpoint = baseURL + "/v1/chat/completions"
+	}
+
+	client, err := getHTTPClient(m.cfg.Proxy)
+	if err != nil {
+		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+m.cfg.APIKey)
+	for name, value := range m.cfg.ExtraHeaders {
+		req.Header.Set(name, value)
+	}
 
-	client := getHTTPClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("请求失败: %w", err)
@@ -175,36 +234,107 @@ func (m *UnifiedModel) Name() string {
 	return m.cfg.Name
 }
 
+// Timeout 单次请求的超时
+func (m *UnifiedModel) Timeout() time.Duration {
+	if m.cfg.Timeout > 0 {
+		return time.Duration(m.cfg.Timeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+// temperature 采样温度
+func (m *UnifiedModel) temperature() *float64 {
+	if m.cfg.Temperature != nil {
+		return m.cfg.Temperature
+	}
+	temperature := defaultTemperature
+	return &temperature
+}
+
+// maxTokens 最大输出 token 数
+func (m *UnifiedModel) maxTokens() int {
+	if m.cfg.MaxTokens > 0 {
+		return m.cfg.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// marshalRequest 序列化请求，并合并模型配置的额外参数（额外参数优先）
+func (m *UnifiedModel) marshalRequest(reqBody ChatRequest) ([]byte, error) {
+	data, err := json.Marshal(reqBody)
+	if err != nil || len(m.cfg.ExtraBody) == 0 {
+		return data, err
+	}
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	for key, value := range m.cfg.ExtraBody {
+		body[key] = value
+	}
+	return json.Marshal(body)
+}
+
 // ModelManager 模型管理器
 type ModelManager struct {
-	models []*UnifiedModel
-}
+	mu      sync.Mutex
+	models  []*UnifiedModel
+	latency map[string]time.Duration // 各模型最近一次成功请求的耗时
 
-// NewModelManager 创建模型管理器
-func NewModelManager() *ModelManager {
-	cfg := config.GetConfig()
-	enabledModels := cfg.GetEnabledModels()
+	unsubscribe func()
+	closeOnce   sync.Once
+}
 
+// NewModelManager 创建模型管理器，使用 cfg 中已启用的模型，配置文件修改后自动更新模型列表
+// 创建和更新时清理配置中已没有模型使用的 HTTP 客户端
+func NewModelManager(cfg *config.Config) *ModelManager {
 	manager := &ModelManager{
-		models: make([]*UnifiedModel, 0, len(enabledModels)),
+		latency: make(map[string]time.Duration),
 	}
+	manager.setModels(cfg.GetEnabledModels())
+	pruneHTTPClients(cfg.GetModels())
+	manager.unsubscribe = cfg.Subscribe(func(_, _ config.ConfigFile) {
+		manager.setModels(cfg.GetEnabledModels())
+		pruneHTTPClients(cfg.GetModels())
+	})
 
+	return manager
+}
+
+// Close 停止跟随配置更新
+func (m *ModelManager) Close() {
+	m.closeOnce.Do(m.unsubscribe)
+}
+
+// setModels 替换模型列表，已测得的耗时按模型名称保留
+func (m *ModelManager) setModels(enabledModels []config.ModelConfig) {
+	list := make([]*UnifiedModel, 0, len(enabledModels))
 	for _, modelCfg := range enabledModels {
-		manager.models = append(manager.models, NewUnifiedModel(modelCfg))
+		list = append(list, NewUnifiedModel(modelCfg))
 	}
 
-	return manager
+	m.mu.Lock()
+	m.models = list
+	m.mu.Unlock()
+}
+
+// snapshot 当前的模型列表
+func (m *ModelManager) snapshot() []*UnifiedModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.models
 }
 
 // GetAnswer 获取答案（自动fallback到下一个模型）
 func (m *ModelManager) GetAnswer(ctx context.Context, question string) (string, error) {
-	if len(m.models) == 0 {
+	models := m.snapshot()
+	if len(models) == 0 {
 		return "", fmt.Errorf("没有可用的模型，请先配置模型API Key")
 	}
 
 	var lastErr error
-	for _, model := range m.models {
-		answer, err := model.GetAnswer(ctx, question)
+	for _, model := range models {
+		answer, err := m.timedAnswer(ctx, model, question)
 		if err == nil && answer != "" {
 			return answer, nil
 		}
@@ -215,15 +345,88 @@ func (m *ModelManager) GetAnswer(ctx context.Context, question string) (string,
 	return "", fmt.Errorf("所有模型都调用失败: %v", lastErr)
 }
 
+// GetAnswerFastest 只使用响应最快的模型获取答案（时间紧张时使用，不再逐个尝试）
+func (m *ModelManager) GetAnswerFastest(ctx context.Context, question string) (string, error) {
+	model := m.fastestModel()
+	if model == nil {
+		return "", fmt.Errorf("没有可用的模型，请先配置模型API Key")
+	}
+	return m.timedAnswer(ctx, model, question)
+}
+
+// FastestModelName 获取当前响应最快的模型名称
+func (m *ModelManager) FastestModelName() string {
+	model := m.fastestModel()
+	if model == nil {
+		return ""
+	}
+	return model.Name()
+}
+
+// fastestModel 按最近一次成功请求耗时选出最快的模型，尚未测得耗时的模型按配置顺序排在后面
+func (m *ModelManager) fastestModel() *UnifiedModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.models) == 0 {
+		return nil
+	}
+
+	var best *UnifiedModel
+	var bestLatency time.Duration
+	for _, model := range m.models {
+		latency, ok := m.latency[model.Name()]
+		if !ok {
+			continue
+		}
+		if best == nil || latency < bestLatency {
+			best, bestLatency = model, latency
+		}
+	}
+	if best == nil {
+		return m.models[0]
+	}
+	return best
+}
+
+// timedAnswer 调用模型并记录成功请求的耗时
+func (m *ModelManager) timedAnswer(ctx context.Context, model *UnifiedModel, question string) (string, error) {
+	start := time.Now()
+	answer, err := model.GetAnswer(ctx, question)
+	if err == nil && answer != "" {
+		m.mu.Lock()
+		m.latency[model.Name()] = time.Since(start)
+		m.mu.Unlock()
+	}
+	return answer, err
+}
+
+// Timeout 一次提问的总超时：快速模式下为最快模型的超时，否则为依次尝试所有模型的超时之和
+func (m *ModelManager) Timeout(fast bool) time.Duration {
+	if fast {
+		if model := m.fastestModel(); model != nil {
+			return model.Timeout()
+		}
+		return defaultTimeout
+	}
+
+	var total time.Duration
+	for _, model := range m.snapshot() {
+		total += model.Timeout()
+	}
+	return max(total, defaultTimeout)
+}
+
 // HasAvailableModel 检查是否有可用模型
 func (m *ModelManager) HasAvailableModel() bool {
-	return len(m.models) > 0
+	return len(m.snapshot()) > 0
 }
 
 // GetModelNames 获取可用模型名称列表
 func (m *ModelManager) GetModelNames() []string {
-	names := make([]string, len(m.models))
-	for i, model := range m.models {
+	models := m.snapshot()
+	names := make([]string, len(models))
+	for i, model := range models {
 		names[i] = model.Name()
 	}
 	return names
@@ -0,0 +1,465 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"mosoteach/internal/config"
+)
+
+// 题库发现方式
+const (
+	DiscoveryAuto    = "auto"    // 先用 HTTP，结果为空或失败时改用浏览器
+	DiscoveryHTTP    = "http"    // 只用 HTTP
+	DiscoveryBrowser = "browser" // 只用浏览器
+)
+
+// Discoverer 题库发现接口，HTTP 和浏览器两种实现输出相同的 QuizInfo
+type Discoverer interface {
+	// Name 实现名称，用于日志
+	Name() string
+	// Discover 获取进行中的测验
+	Discover(ctx context.Context, opts DiscoverOptions) ([]QuizInfo, error)
+}
+
+// DiscoverOptions 题库发现选项
+type DiscoverOptions struct {
+	CourseID string // 只刷新指定课程（不受筛选设置影响），为空时按筛选设置刷新
+	Full     bool   // 忽略缓存，重新访问所有测验确认页
+}
+
+// PageFetcher 获取页面并解析为文档，由各发现方式实现
+type PageFetcher interface {
+	FetchPage(ctx context.Context, pageURL string) (*goquery.Document, error)
+}
+
+// ConcurrentFetcher 支持同时获取多个页面的 PageFetcher（浏览器只有一个标签页，不实现该接口）
+type ConcurrentFetcher interface {
+	Concurrency() int
+}
+
+// ProgressFunc 发现过程的进度回调，total 为 0 时表示普通日志
+type ProgressFunc func(message string, progress, total int)
+
+// CourseEntry 课程列表中的课程
+type CourseEntry struct {
+	ID      string
+	Name    string
+	Teacher string
+	Status  string // 课程状态 (data-status)，OPEN 为开放
+	URL     string // 课程互动页面地址
+}
+
+// ParseCourses 解析课程列表页中的所有课程（包括已归档的课程）
+func ParseCourses(doc *goquery.Document) []CourseEntry {
+	var courses []CourseEntry
+	doc.Find("li.class-item").Each(func(i int, s *goquery.Selection) {
+		status, _ := s.Attr("data-status")
+		id, _ := s.Attr("data-id")
+		if id == "" {
+			return
+		}
+
+		name := strings.TrimSpace(s.Find(".class-info-subject").First().Text())
+		if name == "" {
+			name = "未命名课程"
+		}
+		link, _ := s.Attr("data-url")
+		if link == "" {
+			link = interactionURL + "&clazz_course_id=" + id
+		}
+		teacher := strings.TrimSpace(s.Find(".class-info-teacher, .teacher-name, .class-teacher").First().Text())
+		courses = append(courses, CourseEntry{ID: id, Name: name, Teacher: teacher, Status: status, URL: link})
+	})
+	return courses
+}
+
+// selectCourses 按课程的包含/排除设置筛选课程，置顶课程排在前面
+func selectCourses(cfg *config.Config, courses []CourseEntry) []CourseEntry {
+	var selected []CourseEntry
+	for _, course := range courses {
+		if cfg.CourseSelected(course.ID, course.Status == config.CourseStatusOpen) {
+			selected = append(selected, course)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return cfg.IsCoursePinned(selected[i].ID) && !cfg.IsCoursePinned(selected[j].ID)
+	})
+	return selected
+}
+
+// saveCourses 保存课程列表，refreshed 为本次成功获取了互动页面的课程
+func saveCourses(cfg *config.Config, courses []CourseEntry, refreshed []string, now int64) {
+	list := make([]config.DiscoveredCourse, 0, len(courses))
+	for _, course := range courses {
+		list = append(list, config.DiscoveredCourse{
+			ID:      course.ID,
+			Name:    course.Name,
+			Teacher: course.Teacher,
+			Status:  course.Status,
+			URL:     course.URL,
+		})
+	}
+	if err := cfg.SaveCourses(list, refreshed, now); err != nil {
+		slog.Debug("保存课程列表失败", "error", err)
+	}
+}
+
+// ParseQuizRows 解析课程互动页面中进行中的测验，URL 为测验确认页地址
+func ParseQuizRows(doc *goquery.Document, course CourseEntry) []QuizInfo {
+	var quizzes []QuizInfo
+	doc.Find("div.interaction-row").Each(func(i int, row *goquery.Selection) {
+		// 只处理进行中的测验 (data-type="QUIZ", data-row-status="IN_PRGRS")
+		if dataType, _ := row.Attr("data-type"); dataType != "QUIZ" {
+			return
+		}
+		if rowStatus, _ := row.Attr("data-row-status"); rowStatus != "IN_PRGRS" {
+			return
+		}
+
+		quizID, _ := row.Attr("data-id")
+		if quizID == "" {
+			return
+		}
+
+		// 获取题库名称 (优先从data-title获取，否则从span.interaction-name)
+		quizName, _ := row.Attr("data-title")
+		if quizName == "" {
+			quizName = strings.TrimSpace(row.Find("span.interaction-name").Text())
+		}
+		if quizName == "" {
+			quizName = "未命名题库"
+		}
+
+		quizzes = append(quizzes, QuizInfo{
+			URL:        ConfirmURL(course.ID, quizID),
+			CourseID:   course.ID,
+			CourseName: course.Name,
+			QuizID:     quizID,
+			Name:       quizName,
+			QuizMeta:   ParseQuizMeta(row.Text()),
+		})
+	})
+	return quizzes
+}
+
+// ParseQuizURL 从测验确认页中获取真正的答题地址，找不到时返回页面上的跳转链接
+func ParseQuizURL(doc *goquery.Document) (quizURL, link string) {
+	quizURL = strings.TrimSpace(doc.Find("div.hidden-box.hidden-url").Text())
+	if quizURL == "" {
+		link, _ = doc.Find("div.can-operate-color a").Attr("href")
+	}
+	return quizURL, link
+}
+
+// ConfirmURL 测验确认页地址
+func ConfirmURL(courseID, quizID string) string {
+	return quizConfirmPre + courseID + "&id=" + quizID + "&order_item=group"
+}
+
+// DiscoverQuizzes 通用的题库发现流程：课程列表 → 各课程互动页 → 测验确认页
+// 上次已经获取过答题链接的测验直接使用缓存，只有新出现的测验才访问确认页
+func DiscoverQuizzes(ctx context.Context, fetcher PageFetcher, cfg *config.Config, opts DiscoverOptions, report ProgressFunc) ([]QuizInfo, error) {
+	if report == nil {
+		report = func(message string, progress, total int) {
+			slog.Debug(message)
+		}
+	}
+
+	report("正在获取课程列表...", 0, 0)
+	doc, err := fetcher.FetchPage(ctx, courseURL)
+	if err != nil {
+		return nil, fmt.Errorf("获取课程列表失败: %w", err)
+	}
+	allCourses := ParseCourses(doc)
+
+	// 刷新单个课程时不受筛选设置影响
+	var courses []CourseEntry
+	if opts.CourseID != "" {
+		courses = filterCourse(allCourses, opts.CourseID)
+		if len(courses) == 0 {
+			return nil, fmt.Errorf("课程 %s 不存在", opts.CourseID)
+		}
+	} else {
+		courses = selectCourses(cfg, allCourses)
+		report(fmt.Sprintf("找到 %d 个课程，其中 %d 个参与获取", len(allCourses), len(courses)), 0, 0)
+	}
+
+	workers := 1
+	if cf, ok := fetcher.(ConcurrentFetcher); ok && cf.Concurrency() > 1 {
+		workers = cf.Concurrency()
+	}
+
+	// 第一阶段：遍历课程获取进行中的测验（结果按课程顺序保存，保证去重结果稳定）
+	courseQuizzes := make([][]QuizInfo, len(courses))
+	courseFetched := make([]bool, len(courses))
+	var fetched atomic.Int32
+	err = forEachLimit(ctx, len(courses), workers, func(i int) {
+		course := courses[i]
+		doc, err := fetcher.FetchPage(ctx, course.URL)
+		n := int(fetched.Add(1))
+		report(fmt.Sprintf("正在获取课程 %d/%d: %s", n, len(courses), course.Name), n, len(courses))
+		if err != nil {
+			if ctx.Err() == nil {
+				report(fmt.Sprintf("获取课程 %s 的题库失败: %v", course.Name, err), 0, 0)
+			}
+			return
+		}
+		courseQuizzes[i] = ParseQuizRows(doc, course)
+		courseFetched[i] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []QuizInfo
+	seenQuizIDs := make(map[string]bool)
+	for _, quizzes := range courseQuizzes {
+		for _, quiz := range quizzes {
+			// 同一测验可能出现在多个班级中，按题库ID去重
+			if seenQuizIDs[quiz.QuizID] {
+				report(fmt.Sprintf("  跳过重复题库: %s", quiz.Name), 0, 0)
+				continue
+			}
+			seenQuizIDs[quiz.QuizID] = true
+			pending = append(pending, quiz)
+			report(fmt.Sprintf("  找到题库: %s (课程: %s)", quiz.Name, quiz.CourseName), 0, 0)
+		}
+	}
+
+	// 已有答题链接的测验直接使用缓存
+	resolved := make([]bool, len(pending))
+	var toResolve []int
+	for i := range pending {
+		if !opts.Full && reuseCached(cfg, &pending[i]) {
+			resolved[i] = true
+			continue
+		}
+		toResolve = append(toResolve, i)
+	}
+	report(fmt.Sprintf("共找到 %d 个题库（%d 个使用缓存），正在获取 %d 个新题库的答题链接...", len(pending), len(pending)-len(toResolve), len(toResolve)), 0, 0)
+
+	// 第二阶段：访问新测验的确认页面获取真正的答题URL
+	fetched.Store(0)
+	err = forEachLimit(ctx, len(toResolve), workers, func(k int) {
+		i := toResolve[k]
+		quiz := &pending[i]
+		quizURL, meta, err := resolveQuizURL(ctx, fetcher, *quiz)
+		n := int(fetched.Add(1))
+		report(fmt.Sprintf("获取答题链接 %d/%d: %s", n, len(toResolve), quiz.Name), n, len(toResolve))
+		if err != nil {
+			if ctx.Err() == nil {
+				report(fmt.Sprintf("  获取答题URL失败: %v", err), 0, 0)
+			}
+			return
+		}
+		if quizURL == "" {
+			report(fmt.Sprintf("  未找到答题URL: %s", quiz.Name), 0, 0)
+			return
+		}
+
+		quiz.URL = quizURL
+		quiz.QuizMeta = meta
+		quiz.Completed = cfg.IsQuizCompleted(quiz.CourseID, quiz.QuizID, quizURL)
+		resolved[i] = true
+		slog.Debug("获取测验URL", "name", quiz.Name, "completed", quiz.Completed)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var quizzes []QuizInfo
+	for i, quiz := range pending {
+		if resolved[i] {
+			quizzes = append(quizzes, quiz)
+		}
+	}
+
+	// 记录本次看到的测验，下次刷新时只处理新出现的
+	now := time.Now().Unix()
+	entries := make(map[string]config.CourseCache)
+	var refreshed []string
+	for i, course := range courses {
+		if !courseFetched[i] {
+			continue
+		}
+		refreshed = append(refreshed, course.ID)
+		ids := make([]string, 0, len(courseQuizzes[i]))
+		for _, quiz := range courseQuizzes[i] {
+			ids = append(ids, quiz.QuizID)
+		}
+		entries[course.ID] = config.CourseCache{InteractionIDs: ids, FetchedAt: now}
+	}
+	if err := cfg.UpdateCourseCache(entries); err != nil {
+		slog.Debug("保存课程缓存失败", "error", err)
+	}
+	saveCourses(cfg, allCourses, refreshed, now)
+
+	report(fmt.Sprintf("共获取 %d 个有效答题链接", len(quizzes)), 0, 0)
+	return quizzes, nil
+}
+
+// ResolveQuizzes 按 (课程ID, 题库ID) 在运行时重新获取答题地址
+// 名称和元数据取自上次缓存的题库，获取失败的题库会跳过并通过 report 提示
+func ResolveQuizzes(ctx context.Context, fetcher PageFetcher, cfg *config.Config, refs []QuizRef, report ProgressFunc) ([]QuizInfo, error) {
+	if report == nil {
+		report = func(message string, progress, total int) {
+			slog.Debug(message)
+		}
+	}
+
+	var quizzes []QuizInfo
+	for i, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		quiz := QuizInfo{CourseID: ref.CourseID, QuizID: ref.QuizID}
+		if cached, ok := cfg.GetCachedQuiz(ref.CourseID, ref.QuizID); ok {
+			quiz.CourseName = cached.CourseName
+			quiz.Name = cached.Name
+			quiz.QuizMeta = cached.QuizMeta
+		}
+		if quiz.Name == "" {
+			quiz.Name = "题库 " + ref.QuizID
+		}
+		quiz.URL = ConfirmURL(ref.CourseID, ref.QuizID)
+
+		report(fmt.Sprintf("获取答题链接 %d/%d: %s", i+1, len(refs), quiz.Name), i+1, len(refs))
+		quizURL, meta, err := resolveQuizURL(ctx, fetcher, quiz)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			report(fmt.Sprintf("  获取答题URL失败: %s: %v", quiz.Name, err), 0, 0)
+			continue
+		}
+		if quizURL == "" {
+			report(fmt.Sprintf("  未找到答题URL: %s", quiz.Name), 0, 0)
+			continue
+		}
+
+		quiz.URL = quizURL
+		quiz.QuizMeta = meta
+		quiz.Completed = cfg.IsQuizCompleted(quiz.CourseID, quiz.QuizID, quizURL)
+		quizzes = append(quizzes, quiz)
+	}
+	return quizzes, nil
+}
+
+// filterCourse 只保留指定课程
+func filterCourse(courses []CourseEntry, courseID string) []CourseEntry {
+	for _, course := range courses {
+		if course.ID == courseID {
+			return []CourseEntry{course}
+		}
+	}
+	return nil
+}
+
+// reuseCached 上次刷新已见过且已获取答题链接的测验直接使用缓存
+func reuseCached(cfg *config.Config, quiz *QuizInfo) bool {
+	cache, ok := cfg.GetCourseCache(quiz.CourseID)
+	if !ok || !slices.Contains(cache.InteractionIDs, quiz.QuizID) {
+		return false
+	}
+	cached, ok := cfg.GetCachedQuiz(quiz.CourseID, quiz.QuizID)
+	if !ok || cached.URL == "" {
+		return false
+	}
+
+	quiz.URL = cached.URL
+	quiz.QuizMeta.Merge(cached.QuizMeta)
+	quiz.Completed = cached.Completed
+	return true
+}
+
+// forEachLimit 以最多 workers 个并发对 0..count-1 执行 fn，ctx 取消后不再启动新任务并返回 ctx 错误
+func forEachLimit(ctx context.Context, count, workers int, fn func(i int)) error {
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// resolveQuizURL 访问测验确认页获取答题地址，并用确认页上的元数据补全互动行中缺失的字段
+func resolveQuizURL(ctx context.Context, fetcher PageFetcher, quiz QuizInfo) (string, config.QuizMeta, error) {
+	meta := quiz.QuizMeta
+
+	doc, err := fetcher.FetchPage(ctx, quiz.URL)
+	if err != nil {
+		return "", meta, err
+	}
+	doc.Find("script, style").Remove()
+	meta.Merge(ParseQuizMeta(doc.Find("body").Text()))
+
+	quizURL, link := ParseQuizURL(doc)
+	if quizURL == "" && link != "" {
+		// 尝试从链接获取
+		subDoc, err := fetcher.FetchPage(ctx, link)
+		if err != nil {
+			return "", meta, err
+		}
+		quizURL, _ = ParseQuizURL(subDoc)
+	}
+	return quizURL, meta, nil
+}
+
+// fallbackDiscoverer 主方式失败或结果为空时改用备用方式
+type fallbackDiscoverer struct {
+	primary  Discoverer
+	fallback Discoverer
+	report   ProgressFunc
+}
+
+// WithFallback 组合两种发现方式：primary 失败或没有找到题库时改用 fallback
+func WithFallback(primary, fallback Discoverer, report ProgressFunc) Discoverer {
+	return &fallbackDiscoverer{primary: primary, fallback: fallback, report: report}
+}
+
+func (d *fallbackDiscoverer) Name() string {
+	return d.primary.Name() + " → " + d.fallback.Name()
+}
+
+func (d *fallbackDiscoverer) Discover(ctx context.Context, opts DiscoverOptions) ([]QuizInfo, error) {
+	quizzes, err := d.primary.Discover(ctx, opts)
+	if err == nil && len(quizzes) > 0 {
+		return quizzes, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	reason := "未找到题库"
+	if err != nil {
+		reason = err.Error()
+	}
+	if d.report != nil {
+		d.report(fmt.Sprintf("%s获取题库失败（%s），改用%s", d.primary.Name(), reason, d.fallback.Name()), 0, 0)
+	}
+	return d.fallback.Discover(ctx, opts)
+}
@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"mosoteach/internal/config"
+)
+
+// 云班课页面上的时间均为北京时间
+var siteLocation = time.FixedZone("CST", 8*3600)
+
+var (
+	dateTimePattern     = regexp.MustCompile(`(\d{4})[-/年.](\d{1,2})[-/月.](\d{1,2})日?\s*(\d{1,2}):(\d{2})(?::(\d{2}))?`)
+	deadlineKeyword     = regexp.MustCompile(`(?:截止|结束)(?:时间|日期)?[：:\s]*`)
+	attemptsLeftPattern = regexp.MustCompile(`剩余(?:作答|答题)?(?:机会|次数)[：:\s]*(\d+)`)
+	attemptsRatio       = regexp.MustCompile(`(?:作答|答题)次数[：:\s]*(\d+)\s*/\s*(\d+)`)
+	attemptsMaxPattern  = regexp.MustCompile(`(?:可作答|最多作答|允许作答)\s*(\d+)\s*次`)
+	attemptsUsedPattern = regexp.MustCompile(`已作答\s*(\d+)\s*次`)
+	questionCountRegexp = regexp.MustCompile(`(?:共\s*(\d+)\s*(?:道)?题|(?:题目数|题量|题目数量)[：:\s]*(\d+))`)
+	totalScorePattern   = regexp.MustCompile(`(?:总分|满分|总分值)[：:\s]*(\d+(?:\.\d+)?)`)
+)
+
+// ParseQuizMeta 从互动行或测验确认页的文本中解析截止时间、剩余次数、题目数量和总分
+// HTTP 和浏览器两种获取方式共用，解析不到的字段保持零值
+func ParseQuizMeta(text string) config.QuizMeta {
+	text = strings.Join(strings.Fields(text), " ")
+	var meta config.QuizMeta
+
+	meta.Deadline = parseDeadline(text)
+
+	if m := attemptsLeftPattern.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		meta.AttemptsLeft = &n
+	} else if m := attemptsRatio.FindStringSubmatch(text); m != nil {
+		used, _ := strconv.Atoi(m[1])
+		total, _ := strconv.Atoi(m[2])
+		n := max(total-used, 0)
+		meta.AttemptsLeft = &n
+	} else if m := attemptsMaxPattern.FindStringSubmatch(text); m != nil && !strings.Contains(text, "不限次数") {
+		total, _ := strconv.Atoi(m[1])
+		used := 0
+		if u := attemptsUsedPattern.FindStringSubmatch(text); u != nil {
+			used, _ = strconv.Atoi(u[1])
+		}
+		n := max(total-used, 0)
+		meta.AttemptsLeft = &n
+	}
+
+	if m := questionCountRegexp.FindStringSubmatch(text); m != nil {
+		count := m[1]
+		if count == "" {
+			count = m[2]
+		}
+		meta.QuestionCount, _ = strconv.Atoi(count)
+	}
+
+	if m := totalScorePattern.FindStringSubmatch(text); m != nil {
+		meta.TotalScore, _ = strconv.ParseFloat(m[1], 64)
+	}
+
+	return meta
+}
+
+// parseDeadline 优先取“截止/结束”后面的时间，否则取时间范围中的最后一个时间
+func parseDeadline(text string) int64 {
+	if loc := deadlineKeyword.FindStringIndex(text); loc != nil {
+		if m := dateTimePattern.FindStringSubmatch(text[loc[1]:]); m != nil {
+			if t, ok := parseDateTime(m); ok {
+				return t.Unix()
+			}
+		}
+	}
+
+	matches := dateTimePattern.FindAllStringSubmatch(text, -1)
+	if len(matches) < 2 {
+		return 0
+	}
+	if t, ok := parseDateTime(matches[len(matches)-1]); ok {
+		return t.Unix()
+	}
+	return 0
+}
+
+// parseDateTime 将正则匹配结果转换为时间
+func parseDateTime(m []string) (time.Time, bool) {
+	var parts [6]int
+	for i := 1; i <= 6; i++ {
+		if m[i] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i])
+		if err != nil {
+			return time.Time{}, false
+		}
+		parts[i-1] = n
+	}
+	if parts[1] < 1 || parts[1] > 12 || parts[2] < 1 || parts[2] > 31 {
+		return time.Time{}, false
+	}
+	return time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, siteLocation), true
+}
@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"mosoteach/internal/config"
+)
+
+func TestParseQuizMeta(t *testing.T) {
+	deadline := func(year int, month time.Month, day, hour, min int) int64 {
+		return time.Date(year, month, day, hour, min, 0, 0, siteLocation).Unix()
+	}
+	attempts := func(n int) *int { return &n }
+
+	tests := []struct {
+		name string
+		html string // 互动行或测验确认页的片段，与获取题库时一样取文本后解析
+		want config.QuizMeta
+	}{
+		{
+			name: "互动行：截止时间、剩余次数和题量",
+			html: `<div class="interaction-row" data-type="QUIZ" data-row-status="IN_PRGRS">
+				<span class="interaction-name">第一章测验</span>
+				<span class="time">截止时间：2026-10-20 23:59</span>
+				<span>剩余作答次数：2</span>
+				<span>共 20 题</span>
+			</div>`,
+			want: config.QuizMeta{Deadline: deadline(2026, 10, 20, 23, 59), AttemptsLeft: attempts(2), QuestionCount: 20},
+		},
+		{
+			name: "时间范围取结束时间",
+			html: `<div class="interaction-row"><span>2026/10/01 08:00 - 2026/10/08 18:30</span></div>`,
+			want: config.QuizMeta{Deadline: deadline(2026, 10, 8, 18, 30)},
+		},
+		{
+			name: "中文日期和跨行的文字",
+			html: `<div class="interaction-row"><span>结束</span>
+				<span>2026年10月9日 12:00</span></div>`,
+			want: config.QuizMeta{Deadline: deadline(2026, 10, 9, 12, 0)},
+		},
+		{
+			name: "确认页：已作答次数和总分",
+			html: `<body><div class="quiz-info">
+				<p>作答次数：1/3</p>
+				<p>题目数量：15</p>
+				<p>总分：100.5</p>
+			</div></body>`,
+			want: config.QuizMeta{AttemptsLeft: attempts(2), QuestionCount: 15, TotalScore: 100.5},
+		},
+		{
+			name: "最多作答次数减去已作答次数",
+			html: `<body><p>最多作答 3 次，已作答 3 次</p></body>`,
+			want: config.QuizMeta{AttemptsLeft: attempts(0)},
+		},
+		{
+			name: "不限次数",
+			html: `<body><p>可作答 1 次（不限次数）</p></body>`,
+			want: config.QuizMeta{},
+		},
+		{
+			name: "日期不合法",
+			html: `<div class="interaction-row"><span>截止时间：2026-13-40 10:00</span></div>`,
+			want: config.QuizMeta{},
+		},
+		{
+			name: "没有元数据",
+			html: `<div class="interaction-row"><span class="interaction-name">课堂练习</span></div>`,
+			want: config.QuizMeta{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ParseQuizMeta(doc.Find("body").Text())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuizMeta = %s, want %s", formatMeta(got), formatMeta(tt.want))
+			}
+		})
+	}
+}
+
+// formatMeta 格式化元数据，便于比较剩余次数
+func formatMeta(m config.QuizMeta) string {
+	attempts := "nil"
+	if m.AttemptsLeft != nil {
+		attempts = strconv.Itoa(*m.AttemptsLeft)
+	}
+	return fmt.Sprintf("{Deadline:%s AttemptsLeft:%s QuestionCount:%d TotalScore:%g}",
+		time.Unix(m.Deadline, 0).In(siteLocation).Format("2006-01-02 15:04"), attempts, m.QuestionCount, m.TotalScore)
+}
@@ -1,9 +1,9 @@
 package processor
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"math/rand"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -16,51 +16,59 @@ import (
 )
 
 const (
-	baseURL         = "https://www.mosoteach.cn"
-	courseURL       = "https://www.mosoteach.cn/web/index.php?c=clazzcourse&m=index"
-	interactionURL  = "https://www.mosoteach.cn/web/index.php?c=interaction&m=index"
-	quizConfirmPre  = "https://www.mosoteach.cn/web/index.php?c=interaction_quiz&m=start_quiz_confirm&clazz_course_id="
-	userAgent       = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"
+	baseURL        = "https://www.mosoteach.cn"
+	courseURL      = "https://www.mosoteach.cn/web/index.php?c=clazzcourse&m=index"
+	interactionURL = "https://www.mosoteach.cn/web/index.php?c=interaction&m=index"
+	quizConfirmPre = "https://www.mosoteach.cn/web/index.php?c=interaction_quiz&m=start_quiz_confirm&clazz_course_id="
+	userAgent      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"
 )
 
 // QuizInfo 测试信息
 type QuizInfo struct {
-	URL        string
-	CourseID   string
-	CourseName string // 课程名称
-	QuizID     string
-	Name       string // 题库名称
-	Completed  bool   // 是否已完成
+	URL             string
+	CourseID        string
+	CourseName      string // 课程名称
+	QuizID          string
+	Name            string // 题库名称
+	Completed       bool   // 是否已完成
+	config.QuizMeta        // 截止时间、剩余次数等元数据
+}
+
+// QuizRef 题库的稳定标识，答题地址在运行时重新获取
+type QuizRef struct {
+	CourseID string `json:"courseId"`
+	QuizID   string `json:"quizId"`
 }
 
 // CourseInfo 课程信息（带题库）
 type CourseInfo struct {
-	ID        string     `json:"id"`
-	Name      string     `json:"name"`
-	Quizzes   []QuizInfo `json:"quizzes"`
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Quizzes []QuizInfo `json:"quizzes"`
 }
 
-// DataProcessor 数据处理器
+// DataProcessor 数据处理器（通过 HTTP 获取题库）
 type DataProcessor struct {
 	cfg         *config.Config
 	client      *http.Client
-	courseIDs   []string
-	courseNames []string
-	quizList    []QuizInfo
+	limiter     *RateLimiter
+	concurrency int          // 同时获取的页面数
+	report      ProgressFunc // 可选：进度回调
 }
 
-// NewDataProcessor 创建数据处理器
-func NewDataProcessor() (*DataProcessor, error) {
-	cfg := config.GetConfig()
-
+// NewDataProcessor 创建数据处理器，使用 cfg 中保存的登录Cookie和限速配置
+func NewDataProcessor(cfg *config.Config) (*DataProcessor, error) {
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建cookie jar失败: %w", err)
 	}
 
-	// 解析并设置cookie
+	// 解析并设置cookie（优先使用带作用域的结构化Cookie）
 	baseU, _ := url.Parse(baseURL)
-	cookies := parseCookies(cfg.UserData.Cookie)
+	cookies := savedToHTTPCookies(cfg.GetSavedCookies())
+	if len(cookies) == 0 {
+		cookies = parseCookies(cfg.GetCookie())
+	}
 	jar.SetCookies(baseU, cookies)
 
 	client := &http.Client{
@@ -68,15 +76,35 @@ func NewDataProcessor() (*DataProcessor, error) {
 		Timeout: 30 * time.Second,
 	}
 
+	limit := cfg.GetRateLimit()
 	return &DataProcessor{
 		cfg:         cfg,
 		client:      client,
-		courseIDs:   make([]string, 0),
-		courseNames: make([]string, 0),
-		quizList:    make([]QuizInfo, 0),
+		limiter:     NewRateLimiter(limit.RequestsPerSecond, limit.Burst),
+		concurrency: limit.Concurrency,
 	}, nil
 }
 
+// savedToHTTPCookies 将保存的结构化Cookie转换为 http.Cookie
+func savedToHTTPCookies(saved []config.SavedCookie) []*http.Cookie {
+	cookies := make([]*http.Cookie, 0, len(saved))
+	for _, c := range saved {
+		cookie := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HttpOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		}
+		if c.Expires > 0 {
+			cookie.Expires = time.Unix(int64(c.Expires), 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
 // parseCookies 解析cookie字符串
 func parseCookies(cookieStr string) []*http.Cookie {
 	var cookies []*http.Cookie
@@ -98,8 +126,8 @@ func parseCookies(cookieStr string) []*http.Cookie {
 }
 
 // doRequest 发送HTTP请求
-func (p *DataProcessor) doRequest(method, reqURL, referer string) (*goquery.Document, error) {
-	req, err := http.NewRequest(method, reqURL, nil)
+func (p *DataProcessor) doRequest(ctx context.Context, method, reqURL, referer string) (*goquery.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -122,164 +150,45 @@ func (p *DataProcessor) doRequest(method, reqURL, referer string) (*goquery.Docu
 	return goquery.NewDocumentFromReader(resp.Body)
 }
 
-// FetchCourseList 获取课程列表
-func (p *DataProcessor) FetchCourseList() ([]string, error) {
-	// 检查Cookie是否存在
-	if p.cfg.UserData.Cookie == "" {
-		return nil, fmt.Errorf("Cookie为空，请先运行一次答题任务以获取登录Cookie")
-	}
-
-	doc, err := p.doRequest("GET", courseURL, baseURL)
-	if err != nil {
-		return nil, fmt.Errorf("获取课程列表失败: %w", err)
+// FetchPage 经限速器放行后请求页面，避免请求过快
+func (p *DataProcessor) FetchPage(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, err
 	}
-
-	var courseNames []string
-	var totalItems int
-
-	// 尝试多种选择器
-	// 方式1: 直接查找 li.class-item
-	doc.Find("li.class-item").Each(func(i int, s *goquery.Selection) {
-		totalItems++
-		// 获取状态
-		status, _ := s.Attr("data-status")
-		id, hasID := s.Attr("data-id")
-
-		// 只获取开放的课程 (data-status="OPEN")
-		if status != "OPEN" {
-			return
-		}
-
-		// 获取课程ID
-		if hasID && id != "" {
-			p.courseIDs = append(p.courseIDs, id)
-
-			// 获取课程名称 (class-info-subject)
-			name := strings.TrimSpace(s.Find("span.class-info-subject").Text())
-			if name == "" {
-				// 尝试其他选择器
-				name = strings.TrimSpace(s.Find(".class-info-subject").Text())
-			}
-			if name == "" {
-				name = "未命名课程"
-			}
-			courseNames = append(courseNames, name)
-			slog.Debug("找到课程", "name", name, "id", id)
-		}
-	})
-
-	slog.Debug("课程统计", "total", totalItems, "open", len(p.courseIDs))
-	p.courseNames = courseNames
-	return courseNames, nil
+	return p.doRequest(ctx, "GET", pageURL, baseURL)
 }
 
-// FetchPendingQuizzes 获取待完成的测验
-func (p *DataProcessor) FetchPendingQuizzes() ([]QuizInfo, error) {
-	if len(p.courseIDs) == 0 {
-		_, err := p.FetchCourseList()
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	for i, courseID := range p.courseIDs {
-		// 随机延迟
-		time.Sleep(time.Duration(1000+rand.Intn(2000)) * time.Millisecond)
-
-		interactURL := interactionURL + "&clazz_course_id=" + courseID
-		doc, err := p.doRequest("GET", interactURL, baseURL)
-		if err != nil {
-			continue
-		}
-
-		// 解析互动活动
-		p.parseInteractions(doc, courseID, i)
-	}
-
-	// 获取测验URL
-	return p.fetchQuizURLs()
+// Concurrency 实现 ConcurrentFetcher：限速范围内允许同时获取多个页面
+func (p *DataProcessor) Concurrency() int {
+	return p.concurrency
 }
 
-// parseInteractions 解析互动活动
-func (p *DataProcessor) parseInteractions(doc *goquery.Document, courseID string, index int) {
-	// 查找所有互动行
-	doc.Find("div.interaction-row").Each(func(i int, row *goquery.Selection) {
-		// 检查是否是测验类型 (data-type="QUIZ")
-		dataType, _ := row.Attr("data-type")
-		if dataType != "QUIZ" {
-			return
-		}
-
-		// 检查是否是进行中 (data-row-status="IN_PRGRS")
-		rowStatus, _ := row.Attr("data-row-status")
-		if rowStatus != "IN_PRGRS" {
-			return
-		}
-
-		// 获取题库ID
-		quizID, exists := row.Attr("data-id")
-		if !exists || quizID == "" {
-			return
-		}
-
-		// 获取题库名称 (优先从data-title获取，否则从span.interaction-name)
-		quizName, _ := row.Attr("data-title")
-		if quizName == "" {
-			quizName = strings.TrimSpace(row.Find("span.interaction-name").Text())
-		}
-		if quizName == "" {
-			quizName = "未命名题库"
-		}
-
-		quizURL := quizConfirmPre + courseID + "&id=" + quizID + "&order_item=group"
-		p.quizList = append(p.quizList, QuizInfo{
-			URL:      quizURL,
-			CourseID: courseID,
-			QuizID:   quizID,
-			Name:     quizName,
-		})
-		slog.Debug("找到测验", "name", quizName, "quiz_id", quizID, "course_id", courseID)
-	})
+// SetProgressFunc 设置进度回调
+func (p *DataProcessor) SetProgressFunc(report ProgressFunc) {
+	p.report = report
 }
 
-// fetchQuizURLs 获取测验实际URL
-func (p *DataProcessor) fetchQuizURLs() ([]QuizInfo, error) {
-	var validQuizzes []QuizInfo
-
-	for _, quiz := range p.quizList {
-		time.Sleep(time.Duration(1000+rand.Intn(2000)) * time.Millisecond)
-
-		doc, err := p.doRequest("GET", quiz.URL, baseURL)
-		if err != nil {
-			continue
-		}
-
-		// 获取隐藏的URL
-		hiddenURL := strings.TrimSpace(doc.Find("div.hidden-box.hidden-url").Text())
+// Name 实现 Discoverer
+func (p *DataProcessor) Name() string {
+	return "HTTP"
+}
 
-		if hiddenURL == "" {
-			// 尝试从链接获取
-			if link, exists := doc.Find("div.can-operate-color a").Attr("href"); exists {
-				subDoc, err := p.doRequest("GET", link, baseURL)
-				if err == nil {
-					hiddenURL = strings.TrimSpace(subDoc.Find("div.hidden-box.hidden-url").Text())
-				}
-			}
-		}
+// Discover 通过 HTTP 获取进行中的测验
+func (p *DataProcessor) Discover(ctx context.Context, opts DiscoverOptions) ([]QuizInfo, error) {
+	// 检查Cookie是否存在
+	if p.cfg.GetCookie() == "" && len(p.cfg.GetSavedCookies()) == 0 {
+		return nil, fmt.Errorf("Cookie为空，请先运行一次答题任务以获取登录Cookie")
+	}
 
-		if hiddenURL != "" {
-			completed := p.cfg.IsURLCompleted(hiddenURL)
-			validQuizzes = append(validQuizzes, QuizInfo{
-				URL:       hiddenURL,
-				CourseID:  quiz.CourseID,
-				QuizID:    quiz.QuizID,
-				Name:      quiz.Name,
-				Completed: completed,
-			})
-			slog.Debug("获取测验URL", "name", quiz.Name, "completed", completed)
-		}
+	quizzes, err := DiscoverQuizzes(ctx, p, p.cfg, opts, p.report)
+	if err != nil {
+		return nil, err
 	}
+	slog.Debug("测验统计", "valid", len(quizzes))
+	return quizzes, nil
+}
 
-	slog.Debug("测验统计", "total", len(p.quizList), "valid", len(validQuizzes))
-	return validQuizzes, nil
+// FetchPendingQuizzes 获取待完成的测验
+func (p *DataProcessor) FetchPendingQuizzes(ctx context.Context) ([]QuizInfo, error) {
+	return p.Discover(ctx, DiscoverOptions{})
 }
@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter 令牌桶限速器：按固定速率补充令牌，最多积攒 burst 个
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒补充的令牌数
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time // 当前时间，测试时可替换
+}
+
+// NewRateLimiter 创建限速器，rate 为每秒请求数，burst 为允许的突发请求数
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// Wait 等待获取一个令牌，ctx 取消时立即返回错误
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 尝试取走一个令牌，成功返回 0，否则返回需要等待的时间
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 速率为 0 表示不限速
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	// 差值不足 1 纳秒时也要等待，返回 0 会被当作已取得令牌
+	return max(time.Duration((1-l.tokens)/l.rate*float64(time.Second)), time.Nanosecond)
+}
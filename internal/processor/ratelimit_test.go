@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(rate float64, burst int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := NewRateLimiter(rate, burst)
+	l.last = clock.t
+	l.now = clock.now
+	return l, clock
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		steps []time.Duration // 每次 reserve 前推进的时间
+		want  []time.Duration
+	}{
+		{
+			name:  "突发额度用完后按速率等待",
+			rate:  2,
+			burst: 2,
+			steps: []time.Duration{0, 0, 0},
+			want:  []time.Duration{0, 0, 500 * time.Millisecond},
+		},
+		{
+			name:  "等待后补充令牌",
+			rate:  2,
+			burst: 1,
+			steps: []time.Duration{0, 0, 500 * time.Millisecond},
+			want:  []time.Duration{0, 500 * time.Millisecond, 0},
+		},
+		{
+			name:  "令牌不超过突发上限",
+			rate:  10,
+			burst: 2,
+			steps: []time.Duration{time.Hour, 0, 0},
+			want:  []time.Duration{0, 0, 100 * time.Millisecond},
+		},
+		{
+			name:  "burst 小于 1 按 1 处理",
+			rate:  1,
+			burst: 0,
+			steps: []time.Duration{0, 0},
+			want:  []time.Duration{0, time.Second},
+		},
+		{
+			name:  "速率为 0 不限速",
+			rate:  0,
+			burst: 1,
+			steps: []time.Duration{0, 0, 0},
+			want:  []time.Duration{0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(tt.rate, tt.burst)
+			for i, step := range tt.steps {
+				clock.advance(step)
+				if got := l.reserve(); got != tt.want[i] {
+					t.Fatalf("第 %d 次 reserve() = %v，期望 %v", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// 剩余等待时间不足 1 纳秒时不能返回 0，否则会在没有取走令牌的情况下放行
+func TestRateLimiterReserveSubNanosecond(t *testing.T) {
+	l, _ := newTestLimiter(1, 1)
+	l.tokens = 1 - 1e-12
+
+	if got := l.reserve(); got != time.Nanosecond {
+		t.Fatalf("reserve() = %v，期望 %v", got, time.Nanosecond)
+	}
+	if l.tokens >= 1 {
+		t.Fatalf("没有取得令牌时令牌数不应减少到可用状态: %v", l.tokens)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(1000, 1)
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 5 {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatalf("Wait() 出错: %v", err)
+		}
+	}
+	// 突发 1 个，之后每个令牌 1ms
+	if elapsed := time.Since(start); elapsed < 4*time.Millisecond {
+		t.Fatalf("5 次请求只用了 %v，限速没有生效", elapsed)
+	}
+}
+
+func TestRateLimiterWaitCancel(t *testing.T) {
+	l := NewRateLimiter(0.001, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("第一次 Wait() 应立即返回: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() = %v，期望 %v", err, context.DeadlineExceeded)
+	}
+}
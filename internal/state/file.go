@@ -0,0 +1,403 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxRuns 最多保留的运行记录数
+const maxRuns = 100
+
+// fileData 状态文件结构
+type fileData struct {
+	Session       Session                     `json:"session"`
+	SealedSession string                      `json:"sealed_session,omitempty"` // 加密后的登录会话（设置了 Cipher 时使用）
+	Courses       []Course                    `json:"courses,omitempty"`
+	CachedQuizzes []CachedQuiz                `json:"cached_quizzes,omitempty"`
+	CourseCache   map[string]CourseCache      `json:"course_cache,omitempty"`
+	Completions   map[string]CompletionRecord `json:"completions,omitempty"`      // QuizKey → 完成记录
+	LegacyURLs    []string                    `json:"legacy_completed,omitempty"` // 旧版记录中无法对应到题库的答题地址
+	Runs          []RunRecord                 `json:"runs,omitempty"`
+	QuestionBank  map[string]BankAnswer       `json:"question_bank,omitempty"` // 题目指纹 → 答案
+}
+
+// FileStore JSON 状态存储，写入时先写临时文件再重命名，避免写到一半损坏
+// 完成记录、运行记录、题库答案和增量刷新记录只追加到日志（<path>.log），日志达到上限或其他状态变化时才重写整个文件
+type FileStore struct {
+	mu             sync.RWMutex
+	path           string
+	opened         bool
+	data           fileData
+	cipher         Cipher // 可选：加密登录会话
+	journalEntries int    // 日志中的条数
+}
+
+// NewFileStore 创建状态存储，调用 Open 后才会读取文件
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path, data: emptyData()}
+}
+
+func emptyData() fileData {
+	return fileData{
+		CourseCache:  make(map[string]CourseCache),
+		Completions:  make(map[string]CompletionRecord),
+		QuestionBank: make(map[string]BankAnswer),
+	}
+}
+
+// Path 状态文件路径
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Open 实现 Store
+func (s *FileStore) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.opened {
+		return nil
+	}
+
+	raw, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取状态文件失败: %w", err)
+	}
+	data := emptyData()
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return fmt.Errorf("解析状态文件失败: %w", err)
+		}
+		if data.CourseCache == nil {
+			data.CourseCache = make(map[string]CourseCache)
+		}
+		if data.Completions == nil {
+			data.Completions = make(map[string]CompletionRecord)
+		}
+		if data.QuestionBank == nil {
+			data.QuestionBank = make(map[string]BankAnswer)
+		}
+	}
+
+	plaintext := data.SealedSession == "" && (data.Session.Cookie != "" || len(data.Session.Cookies) > 0)
+	if data.SealedSession != "" {
+		if s.cipher == nil {
+			return fmt.Errorf("状态文件中的登录会话已加密，但没有提供密钥")
+		}
+		raw, err := s.cipher.Open(data.SealedSession)
+		if err != nil {
+			return fmt.Errorf("解密登录会话失败: %w", err)
+		}
+		if err := json.Unmarshal(raw, &data.Session); err != nil {
+			return fmt.Errorf("解析登录会话失败: %w", err)
+		}
+		data.SealedSession = ""
+	}
+
+	s.data = data
+	if err := s.replayJournal(); err != nil {
+		return fmt.Errorf("读取状态日志失败: %w", err)
+	}
+	s.opened = true
+
+	// 旧版明文保存的会话改为加密保存
+	if plaintext && s.cipher != nil {
+		return s.save()
+	}
+	return nil
+}
+
+// SetCipher 实现 Store
+func (s *FileStore) SetCipher(cipher Cipher) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cipher = cipher
+	if !s.opened {
+		return nil
+	}
+	return s.save()
+}
+
+// save 写入完整的状态文件并清空日志（调用方持有锁）
+func (s *FileStore) save() error {
+	data := s.data
+	if s.cipher != nil {
+		session, err := json.Marshal(data.Session)
+		if err != nil {
+			return err
+		}
+		if data.SealedSession, err = s.cipher.Seal(session); err != nil {
+			return fmt.Errorf("加密登录会话失败: %w", err)
+		}
+		data.Session = Session{}
+	}
+
+	raw, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	return s.truncateJournal()
+}
+
+// Session 实现 Store
+func (s *FileStore) Session() Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session := s.data.Session
+	session.Cookies = append([]SavedCookie(nil), session.Cookies...)
+	return session
+}
+
+// SaveSession 实现 Store
+func (s *FileStore) SaveSession(session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.UpdatedAt = time.Now().Unix()
+	s.data.Session = session
+	return s.save()
+}
+
+// CachedQuizzes 实现 Store
+func (s *FileStore) CachedQuizzes() []CachedQuiz {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]CachedQuiz, len(s.data.CachedQuizzes))
+	copy(result, s.data.CachedQuizzes)
+	return result
+}
+
+// SaveCachedQuizzes 实现 Store
+func (s *FileStore) SaveCachedQuizzes(quizzes []CachedQuiz) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.CachedQuizzes = quizzes
+	return s.save()
+}
+
+// Courses 实现 Store
+func (s *FileStore) Courses() []Course {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Course, len(s.data.Courses))
+	copy(result, s.data.Courses)
+	return result
+}
+
+// SaveCourses 实现 Store
+func (s *FileStore) SaveCourses(courses []Course) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Courses = courses
+	return s.save()
+}
+
+// CourseCache 实现 Store
+func (s *FileStore) CourseCache(courseID string) (CourseCache, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cache, ok := s.data.CourseCache[courseID]
+	return cache, ok
+}
+
+// UpdateCourseCache 实现 Store
+func (s *FileStore) UpdateCourseCache(entries map[string]CourseCache) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(journalEntry{CourseCache: entries})
+}
+
+// IsCompleted 实现 Store
+func (s *FileStore) IsCompleted(courseID, quizID, url string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Completions[QuizKey(courseID, quizID)]; ok {
+		return true
+	}
+	if url == "" {
+		return false
+	}
+	for _, legacy := range s.data.LegacyURLs {
+		if legacy == url {
+			return true
+		}
+	}
+	return false
+}
+
+// Completions 实现 Store
+func (s *FileStore) Completions() []CompletionRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]CompletionRecord, 0, len(s.data.Completions))
+	for _, rec := range s.data.Completions {
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CompletedAt < records[j].CompletedAt
+	})
+	return records
+}
+
+// AddCompletion 实现 Store
+func (s *FileStore) AddCompletion(rec CompletionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec.CompletedAt == 0 {
+		rec.CompletedAt = time.Now().Unix()
+	}
+	return s.commit(journalEntry{Completion: &rec})
+}
+
+// Runs 实现 Store
+func (s *FileStore) Runs(limit int) []RunRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := len(s.data.Runs)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+	result := make([]RunRecord, 0, count)
+	for i := len(s.data.Runs) - 1; i >= 0 && len(result) < count; i-- {
+		result = append(result, s.data.Runs[i])
+	}
+	return result
+}
+
+// AddRun 实现 Store
+func (s *FileStore) AddRun(run RunRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(journalEntry{Run: &run})
+}
+
+// LookupAnswer 实现 Store
+func (s *FileStore) LookupAnswer(key string) (BankAnswer, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	answer, ok := s.data.QuestionBank[key]
+	return answer, ok
+}
+
+// SaveAnswers 实现 Store
+func (s *FileStore) SaveAnswers(answers map[string]BankAnswer) error {
+	if len(answers) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(journalEntry{Answers: answers})
+}
+
+// Import 实现 Store
+func (s *FileStore) Import(legacy Legacy) error {
+	if legacy.Empty() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.Session.Cookie == "" && len(s.data.Session.Cookies) == 0 {
+		s.data.Session = Session{Cookie: legacy.Cookie, Cookies: legacy.Cookies, UpdatedAt: time.Now().Unix()}
+	}
+	if len(s.data.CachedQuizzes) == 0 {
+		s.data.CachedQuizzes = legacy.CachedQuizzes
+	}
+	if len(s.data.Courses) == 0 {
+		s.data.Courses = legacy.Courses
+	}
+	for id, cache := range legacy.CourseCache {
+		if _, ok := s.data.CourseCache[id]; !ok {
+			s.data.CourseCache[id] = cache
+		}
+	}
+	for _, rec := range legacy.Completed {
+		key := QuizKey(rec.CourseID, rec.QuizID)
+		if _, ok := s.data.Completions[key]; !ok {
+			s.data.Completions[key] = rec
+		}
+	}
+	// 旧版按答题地址记录：能在缓存中找到对应题库的转换为完成记录
+	for _, url := range legacy.CompletedURLs {
+		if !s.importCompletedURL(url) {
+			s.data.LegacyURLs = append(s.data.LegacyURLs, url)
+		}
+	}
+	for key, answer := range legacy.QuestionBank {
+		if _, ok := s.data.QuestionBank[key]; !ok {
+			s.data.QuestionBank[key] = BankAnswer{Answer: answer}
+		}
+	}
+	return s.save()
+}
+
+// importCompletedURL 将旧版的已完成地址转换为完成记录（调用方持有锁）
+func (s *FileStore) importCompletedURL(url string) bool {
+	for _, q := range s.data.CachedQuizzes {
+		if q.URL != url || q.QuizID == "" {
+			continue
+		}
+		key := QuizKey(q.CourseID, q.QuizID)
+		if _, ok := s.data.Completions[key]; !ok {
+			s.data.Completions[key] = CompletionRecord{
+				CourseID:   q.CourseID,
+				QuizID:     q.QuizID,
+				CourseName: q.CourseName,
+				Name:       q.Name,
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"slices"
+)
+
+// maxJournalEntries 日志条数达到上限时把全部状态写入快照并清空日志
+const maxJournalEntries = 500
+
+// journalEntry 日志中的一条增量修改
+// 快照写入后、日志清空前崩溃时会重放快照中已包含的修改，所以重放必须是幂等的
+type journalEntry struct {
+	Completion  *CompletionRecord      `json:"completion,omitempty"`
+	Run         *RunRecord             `json:"run,omitempty"`
+	Answers     map[string]BankAnswer  `json:"answers,omitempty"`
+	CourseCache map[string]CourseCache `json:"course_cache,omitempty"`
+}
+
+// JournalPath 状态文件对应的增量日志路径
+func JournalPath(path string) string {
+	return path + ".log"
+}
+
+// Remove 删除状态文件及其增量日志
+func Remove(path string) error {
+	for _, p := range []string{path, JournalPath(path)} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEntry 将增量修改应用到内存中的状态（调用方持有锁）
+func (s *FileStore) applyEntry(e journalEntry) {
+	if rec := e.Completion; rec != nil {
+		s.data.Completions[QuizKey(rec.CourseID, rec.QuizID)] = *rec
+	}
+	if run := e.Run; run != nil && !slices.ContainsFunc(s.data.Runs, func(r RunRecord) bool { return r.ID == run.ID }) {
+		s.data.Runs = append(s.data.Runs, *run)
+		if len(s.data.Runs) > maxRuns {
+			s.data.Runs = s.data.Runs[len(s.data.Runs)-maxRuns:]
+		}
+	}
+	for key, answer := range e.Answers {
+		if old, ok := s.data.QuestionBank[key]; ok && old.Verified && !answer.Verified {
+			continue
+		}
+		s.data.QuestionBank[key] = answer
+	}
+	for id, cache := range e.CourseCache {
+		s.data.CourseCache[id] = cache
+	}
+}
+
+// commit 应用增量修改并追加到日志，不重写整个状态文件（调用方持有锁）
+func (s *FileStore) commit(e journalEntry) error {
+	s.applyEntry(e)
+	if s.journalEntries >= maxJournalEntries {
+		return s.save()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(JournalPath(s.path), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.journalEntries++
+	return nil
+}
+
+// replayJournal 在快照之上重放增量日志（调用方持有锁）
+// 写到一半崩溃时最后一行可能不完整，从无法解析的行开始忽略，下次写入快照时丢弃
+func (s *FileStore) replayJournal() error {
+	raw, err := os.ReadFile(JournalPath(s.path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(nil, len(raw)+1)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			break
+		}
+		s.applyEntry(e)
+		s.journalEntries++
+	}
+	return nil
+}
+
+// truncateJournal 快照已包含全部状态后清空日志（调用方持有锁）
+func (s *FileStore) truncateJournal() error {
+	if err := os.Remove(JournalPath(s.path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.journalEntries = 0
+	return nil
+}
@@ -0,0 +1,203 @@
+package state
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Store 运行状态存储：登录会话、题库缓存、完成记录、运行历史和题库答案
+// 与用户配置（user_data.json）分开保存，频繁写入不会覆盖用户手动修改的配置
+type Store interface {
+	// Open 首次调用时从磁盘加载状态，之后的调用直接返回
+	Open() error
+	// SetCipher 设置加密登录会话使用的密钥，已加载时用新密钥重新保存
+	SetCipher(cipher Cipher) error
+
+	// Session 获取登录会话
+	Session() Session
+	// SaveSession 保存登录会话
+	SaveSession(session Session) error
+
+	// CachedQuizzes 获取缓存的题库（不含完成状态）
+	CachedQuizzes() []CachedQuiz
+	// SaveCachedQuizzes 替换缓存的题库
+	SaveCachedQuizzes(quizzes []CachedQuiz) error
+	// Courses 获取上次发现的课程列表
+	Courses() []Course
+	// SaveCourses 替换发现的课程列表
+	SaveCourses(courses []Course) error
+	// CourseCache 获取课程的增量刷新记录
+	CourseCache(courseID string) (CourseCache, bool)
+	// UpdateCourseCache 更新课程的增量刷新记录
+	UpdateCourseCache(entries map[string]CourseCache) error
+
+	// IsCompleted 检查题库是否已完成，url 为可选的答题地址（用于兼容旧版记录）
+	IsCompleted(courseID, quizID, url string) bool
+	// Completions 获取完成记录（按完成时间排序）
+	Completions() []CompletionRecord
+	// AddCompletion 添加完成记录
+	AddCompletion(rec CompletionRecord) error
+
+	// Runs 获取最近的运行记录（新的在前），limit 为 0 时返回全部
+	Runs(limit int) []RunRecord
+	// AddRun 添加运行记录
+	AddRun(run RunRecord) error
+
+	// LookupAnswer 查找题目答案
+	LookupAnswer(key string) (BankAnswer, bool)
+	// SaveAnswers 保存题目答案，未经验证的答案不会覆盖已验证的答案
+	SaveAnswers(answers map[string]BankAnswer) error
+
+	// Import 导入旧版配置文件中的运行状态（已有的数据优先）
+	Import(legacy Legacy) error
+}
+
+// Cipher 加密状态文件中的敏感数据（登录会话）
+type Cipher interface {
+	Seal(plaintext []byte) (string, error)
+	Open(sealed string) ([]byte, error)
+}
+
+// SavedCookie 保存的浏览器Cookie（带作用域和过期时间）
+type SavedCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires,omitempty"` // 过期时间（Unix 秒），0 表示会话 Cookie
+	HTTPOnly bool    `json:"http_only,omitempty"`
+	Secure   bool    `json:"secure,omitempty"`
+	SameSite string  `json:"same_site,omitempty"`
+}
+
+// IsExpired 检查Cookie是否已过期（会话 Cookie 永不过期）
+func (c SavedCookie) IsExpired(now time.Time) bool {
+	return c.Expires > 0 && float64(now.Unix()) >= c.Expires
+}
+
+// Session 登录会话
+type Session struct {
+	Cookie    string        `json:"cookie,omitempty"`  // name=value 形式的Cookie字符串，用于 HTTP 请求
+	Cookies   []SavedCookie `json:"cookies,omitempty"` // 结构化Cookie，用于浏览器恢复登录
+	UpdatedAt int64         `json:"updated_at,omitempty"`
+}
+
+// QuizMeta 题库元数据（从互动列表和测验确认页解析）
+type QuizMeta struct {
+	Deadline      int64   `json:"deadline,omitempty"`       // 截止时间（Unix 秒，0 表示未知）
+	AttemptsLeft  *int    `json:"attempts_left,omitempty"`  // 剩余作答次数（nil 表示未知或不限）
+	QuestionCount int     `json:"question_count,omitempty"` // 题目数量
+	TotalScore    float64 `json:"total_score,omitempty"`    // 总分
+}
+
+// Merge 用 other 中的值补全缺失的字段
+func (m *QuizMeta) Merge(other QuizMeta) {
+	if m.Deadline == 0 {
+		m.Deadline = other.Deadline
+	}
+	if m.AttemptsLeft == nil {
+		m.AttemptsLeft = other.AttemptsLeft
+	}
+	if m.QuestionCount == 0 {
+		m.QuestionCount = other.QuestionCount
+	}
+	if m.TotalScore == 0 {
+		m.TotalScore = other.TotalScore
+	}
+}
+
+// CachedQuiz 缓存的题库
+type CachedQuiz struct {
+	URL        string `json:"url"`
+	CourseID   string `json:"course_id"`
+	CourseName string `json:"course_name"`
+	QuizID     string `json:"quiz_id"`
+	Name       string `json:"name"`
+	Completed  bool   `json:"completed"`
+	QuizMeta
+}
+
+// Course 从课程列表页发现的课程（筛选和置顶等用户设置保存在配置文件中）
+type Course struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Teacher     string `json:"teacher,omitempty"`
+	Status      string `json:"status"`                 // 课程状态（OPEN 为开放，其他为已归档/结课）
+	URL         string `json:"url,omitempty"`          // 课程互动页面地址
+	LastRefresh int64  `json:"last_refresh,omitempty"` // 上次刷新题库的时间（Unix 秒）
+}
+
+// CourseCache 课程的增量刷新记录
+type CourseCache struct {
+	InteractionIDs []string `json:"interaction_ids"` // 上次看到的进行中测验互动ID
+	FetchedAt      int64    `json:"fetched_at"`      // 上次获取时间（Unix 秒）
+}
+
+// QuizKey 题库的稳定标识（课程ID/题库ID），答题地址在不同会话间可能变化，不能作为标识
+func QuizKey(courseID, quizID string) string {
+	return courseID + "/" + quizID
+}
+
+// CompletionRecord 题库完成记录
+type CompletionRecord struct {
+	CourseID    string `json:"course_id"`
+	QuizID      string `json:"quiz_id"`
+	CourseName  string `json:"course_name,omitempty"`
+	Name        string `json:"name,omitempty"`
+	CompletedAt int64  `json:"completed_at"` // 完成时间（Unix 秒）
+}
+
+// 运行结果
+const (
+	RunComplete  = "complete"
+	RunError     = "error"
+	RunCancelled = "cancelled"
+)
+
+// RunRecord 一次答题运行的记录
+type RunRecord struct {
+	ID         string   `json:"id"`
+	StartedAt  int64    `json:"started_at"`
+	FinishedAt int64    `json:"finished_at"`
+	Quizzes    []string `json:"quizzes,omitempty"` // 指定的题库（课程ID/题库ID），为空表示答所有题库
+	Result     string   `json:"result"`            // complete / error / cancelled
+	Message    string   `json:"message,omitempty"`
+}
+
+// BankAnswer 题库中保存的答案
+// AI 给出的答案在提交前无法确认是否正确，保存为未验证；只有经过结果页确认的答案才标记为已验证
+type BankAnswer struct {
+	Answer   string `json:"answer"`
+	Verified bool   `json:"verified,omitempty"`
+	SavedAt  int64  `json:"saved_at,omitempty"` // 保存时间（Unix 秒）
+}
+
+// UnmarshalJSON 兼容旧版只保存答案字符串的格式，旧数据都按未验证处理
+func (a *BankAnswer) UnmarshalJSON(data []byte) error {
+	var answer string
+	if err := json.Unmarshal(data, &answer); err == nil {
+		*a = BankAnswer{Answer: answer}
+		return nil
+	}
+	type plain BankAnswer
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+// Legacy 旧版配置文件中的运行状态字段，用于一次性迁移
+type Legacy struct {
+	Cookie        string
+	Cookies       []SavedCookie
+	CachedQuizzes []CachedQuiz
+	CourseCache   map[string]CourseCache
+	Completed     []CompletionRecord
+	CompletedURLs []string
+	QuestionBank  map[string]string
+	Courses       []Course
+}
+
+// Empty 是否没有需要迁移的数据
+func (l Legacy) Empty() bool {
+	return l.Cookie == "" && len(l.Cookies) == 0 && len(l.CachedQuizzes) == 0 &&
+		len(l.CourseCache) == 0 && len(l.Completed) == 0 && len(l.CompletedURLs) == 0 &&
+		len(l.QuestionBank) == 0 && len(l.Courses) == 0
+}
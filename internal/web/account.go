@@ -0,0 +1,306 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mosoteach/internal/browser"
+	"mosoteach/internal/config"
+	"mosoteach/internal/processor"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// accountRuntime 单个账号的运行状态：浏览器会话、当前任务和进度，不同账号可以同时答题
+type accountRuntime struct {
+	server  *Server
+	id      string
+	cfg     *config.Config          // 账号的配置视图
+	session *browser.SessionManager // 跨请求复用的浏览器会话
+
+	mu         sync.RWMutex
+	executor   *browser.BrowserExecutor
+	stepper    *browser.Stepper // 调试运行的单步控制器
+	status     *Status
+	cancelFunc context.CancelFunc
+}
+
+// runtime 获取账号的运行状态，首次使用时创建
+func (s *Server) runtime(id string) (*accountRuntime, error) {
+	cfg, err := s.cfg.Account(id)
+	if err != nil {
+		return nil, err
+	}
+	id = cfg.AccountID()
+
+	s.accountsMu.Lock()
+	defer s.accountsMu.Unlock()
+
+	if rt, ok := s.accounts[id]; ok {
+		return rt, nil
+	}
+	rt := &accountRuntime{
+		server:  s,
+		id:      id,
+		cfg:     cfg,
+		session: browser.NewSessionManager(cfg),
+		status: &Status{
+			Running: false,
+			Message: "就绪",
+		},
+	}
+	s.accounts[id] = rt
+	return rt, nil
+}
+
+// requestRuntime 按请求中的账号获取运行状态，id 为空时使用查询参数 account（默认账号为 default）
+// 账号不存在时写入错误响应并返回 nil
+func (s *Server) requestRuntime(w http.ResponseWriter, r *http.Request, id string) *accountRuntime {
+	if id == "" {
+		id = r.URL.Query().Get("account")
+	}
+	rt, err := s.runtime(id)
+	if errors.Is(err, config.ErrAccountNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	return rt
+}
+
+// send 发送账号的进度事件
+func (rt *accountRuntime) send(event ProgressEvent) {
+	event.Account = rt.id
+	rt.server.sendSSEEvent(event)
+}
+
+// progressCallback 进度回调
+func (rt *accountRuntime) progressCallback(event browser.ProgressEvent) {
+	rt.mu.Lock()
+	rt.status.Message = event.Message
+	if event.Total > 0 {
+		rt.status.Total = event.Total
+	}
+	if event.Progress > 0 {
+		rt.status.Progress = event.Progress
+	}
+	rt.status.CurrentTask = event.Message
+	rt.mu.Unlock()
+
+	// 转换为web包的ProgressEvent
+	rt.send(ProgressEvent{
+		Type:         event.Type,
+		Message:      event.Message,
+		Progress:     event.Progress,
+		Total:        event.Total,
+		QuizName:     event.QuizName,
+		QuizProgress: event.QuizProgress,
+		QuizTotal:    event.QuizTotal,
+	})
+}
+
+// quizRefByURL 按旧版的答题地址在缓存中查找题库
+func (rt *accountRuntime) quizRefByURL(quizURL string) (processor.QuizRef, bool) {
+	for _, q := range rt.cfg.GetCachedQuizzes() {
+		if q.URL == quizURL && q.QuizID != "" {
+			return processor.QuizRef{CourseID: q.CourseID, QuizID: q.QuizID}, true
+		}
+	}
+	return processor.QuizRef{}, false
+}
+
+// running 账号是否有任务正在运行
+func (rt *accountRuntime) running() bool {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.status.Running
+}
+
+// logout 登录信息变化后旧的Cookie不再可用，保存的和常驻标签页中的Cookie都要清除
+func (rt *accountRuntime) logout() error {
+	if err := rt.session.Logout(); err != nil {
+		slog.Warn("清除浏览器Cookie失败", "account", rt.id, "error", err)
+	}
+	return rt.cfg.ClearCookies()
+}
+
+// close 取消正在运行的任务并关闭常驻浏览器
+func (rt *accountRuntime) close() {
+	rt.mu.Lock()
+	if rt.cancelFunc != nil {
+		rt.cancelFunc()
+	}
+	if rt.executor != nil {
+		rt.executor.Stop()
+		rt.executor = nil
+	}
+	rt.mu.Unlock()
+	rt.session.Close()
+}
+
+// runtimes 已创建的账号运行状态
+func (s *Server) runtimes() map[string]*accountRuntime {
+	s.accountsMu.Lock()
+	defer s.accountsMu.Unlock()
+	result := make(map[string]*accountRuntime, len(s.accounts))
+	for id, rt := range s.accounts {
+		result[id] = rt
+	}
+	return result
+}
+
+// dropRuntime 移除账号的运行状态并释放资源
+func (s *Server) dropRuntime(id string) {
+	s.accountsMu.Lock()
+	rt, ok := s.accounts[id]
+	delete(s.accounts, id)
+	s.accountsMu.Unlock()
+	if ok {
+		rt.close()
+	}
+}
+
+// accountUserData 配置中账号的登录信息
+func accountUserData(f config.ConfigFile, id string) (config.UserData, bool) {
+	if id == config.DefaultAccount {
+		return f.UserData, true
+	}
+	i := slices.IndexFunc(f.Accounts, func(a config.Account) bool { return a.ID == id })
+	if i < 0 {
+		return config.UserData{}, false
+	}
+	return f.Accounts[i].UserData, true
+}
+
+// reloadAccounts 配置文件重新加载后，登录信息变化的账号需要重新登录，已删除的账号停止运行
+func (s *Server) reloadAccounts(previous, current config.ConfigFile) {
+	for id, rt := range s.runtimes() {
+		before, _ := accountUserData(previous, id)
+		after, ok := accountUserData(current, id)
+		if !ok {
+			s.dropRuntime(id)
+			continue
+		}
+		if after.UserName != before.UserName || after.Password != before.Password {
+			if err := rt.logout(); err != nil {
+				slog.Warn("清除登录会话失败", "account", id, "error", err)
+			}
+		}
+	}
+}
+
+// AccountResponse 账号信息（前端格式，不包含密码）
+type AccountResponse struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	UserName    string   `json:"user_name"`
+	MaskedUser  string   `json:"masked_user"`
+	HasPassword bool     `json:"has_password"`
+	Models      []string `json:"models"`  // 偏好的模型名称
+	Running     bool     `json:"running"` // 是否有任务正在运行
+}
+
+// handleAccounts 获取账号列表（GET）或添加/更新账号（POST）
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		runtimes := s.runtimes()
+		accounts := make([]AccountResponse, 0)
+		for _, a := range s.cfg.ListAccounts() {
+			rt, ok := runtimes[a.ID]
+			accounts = append(accounts, AccountResponse{
+				ID:          a.ID,
+				Name:        a.Name,
+				UserName:    a.UserData.UserName,
+				MaskedUser:  config.MaskUsername(a.UserData.UserName),
+				HasPassword: a.UserData.HasPassword(),
+				Models:      append([]string{}, a.Models...),
+				Running:     ok && rt.running(),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(accounts)
+
+	case http.MethodPost:
+		var req struct {
+			ID       string   `json:"id"`
+			Name     string   `json:"name"`
+			UserName string   `json:"user_name"`
+			Password string   `json:"password"`
+			Models   []string `json:"models"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.ID == "" {
+			req.ID = config.DefaultAccount
+		}
+		changed, err := s.cfg.SaveAccount(config.Account{
+			ID:       req.ID,
+			Name:     req.Name,
+			UserData: config.UserData{UserName: req.UserName, Password: req.Password},
+			Models:   req.Models,
+		})
+		if err != nil {
+			writeSaveError(w, err, http.StatusBadRequest)
+			return
+		}
+		if rt, ok := s.runtimes()[req.ID]; ok && changed {
+			if err := rt.logout(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "账号已保存"})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAccount 删除账号（默认账号不能删除，正在运行的账号需先停止）
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if rt, ok := s.runtimes()[id]; ok && rt.running() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "该账号有任务正在运行中，请先停止",
+		})
+		return
+	}
+
+	if err := s.cfg.RemoveAccount(id); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, config.ErrAccountNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.dropRuntime(id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "账号已删除"})
+}
+
+// readyMessage 检查账号是否可以开始答题
+func (rt *accountRuntime) readyMessage() string {
+	_, message := rt.cfg.IsReady()
+	return message
+}
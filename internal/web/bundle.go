@@ -0,0 +1,112 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"mosoteach/internal/config"
+)
+
+// splitSections 解析逗号分隔的内容列表
+func splitSections(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// handleConfigExport 导出配置包
+// GET 使用查询参数 sections（逗号分隔），敏感字段总是清空；明文和口令加密导出需要 POST 请求体，
+// 避免明文密码出现在地址、浏览器历史和访问日志中
+func (s *Server) handleConfigExport(w http.ResponseWriter, r *http.Request) {
+	var opts config.ExportOptions
+	switch r.Method {
+	case http.MethodGet:
+		opts.Sections = splitSections(r.URL.Query().Get("sections"))
+		opts.Secrets = r.URL.Query().Get("secrets")
+		switch opts.Secrets {
+		case config.SecretsPlain:
+			http.Error(w, "明文导出请使用 POST 请求", http.StatusBadRequest)
+			return
+		case config.SecretsEncrypt:
+			http.Error(w, "加密导出请使用 POST 请求并在请求体中提供口令", http.StatusBadRequest)
+			return
+		}
+	case http.MethodPost:
+		var req struct {
+			Sections   []string `json:"sections"`
+			Secrets    string   `json:"secrets"`
+			Passphrase string   `json:"passphrase"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts = config.ExportOptions{Sections: req.Sections, Secrets: req.Secrets, Passphrase: req.Passphrase}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bundle, err := s.cfg.Export(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := fmt.Sprintf("mosoteach-config-%s.json", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	enc.Encode(bundle)
+}
+
+// handleConfigImport 导入配置包，dry_run 为 true 时只返回差异
+func (s *Server) handleConfigImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Bundle     json.RawMessage `json:"bundle"`
+		Sections   []string        `json:"sections"`
+		Passphrase string          `json:"passphrase"`
+		DryRun     bool            `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bundle, err := config.ParseBundle(req.Bundle)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	changes, err := s.cfg.Import(bundle, config.ImportOptions{
+		Sections:   req.Sections,
+		Passphrase: req.Passphrase,
+		DryRun:     req.DryRun,
+	})
+	if err != nil {
+		writeSaveError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	message := fmt.Sprintf("已导入 %d 项修改", len(changes))
+	if req.DryRun {
+		message = fmt.Sprintf("导入后将修改 %d 项", len(changes))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"dry_run": req.DryRun,
+		"message": message,
+		"changes": changes,
+	})
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// inspectorPath 账号调试浏览器的 DevTools 代理地址前缀
+func inspectorPath(account string) string {
+	return "/api/accounts/" + url.PathEscape(account) + "/inspector"
+}
+
+// handleInspector 代理调试中的浏览器的 DevTools 端点（HTTP 和 WebSocket）
+// DevTools 本身没有认证，只监听本机端口，经过 Web 密码认证后才能通过这里访问
+func (s *Server) handleInspector(w http.ResponseWriter, r *http.Request) {
+	rt := s.requestRuntime(w, r, r.PathValue("id"))
+	if rt == nil {
+		return
+	}
+
+	rt.mu.RLock()
+	stepper := rt.stepper
+	rt.mu.RUnlock()
+
+	addr := ""
+	if stepper != nil {
+		addr = stepper.InspectorAddr()
+	}
+	if addr == "" {
+		http.Error(w, "当前没有可查看的调试浏览器", http.StatusNotFound)
+		return
+	}
+
+	prefix := inspectorPath(rt.id)
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(&url.URL{Scheme: "http", Host: addr})
+			pr.Out.URL.Path = "/" + pr.In.PathValue("path")
+			pr.Out.URL.RawPath = ""
+			// DevTools 只接受本机的 Host，并拒绝带 Origin 的 WebSocket 连接
+			pr.Out.Host = addr
+			pr.Out.Header.Del("Origin")
+			pr.Out.Header.Del("Cookie")
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if !strings.HasPrefix(resp.Request.URL.Path, "/json") {
+				return nil
+			}
+			// 目标列表中的调试地址指向本机端口，改写为经过代理的地址
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return err
+			}
+			body = bytes.ReplaceAll(body, []byte(addr), []byte(r.Host+prefix))
+			body = bytes.ReplaceAll(body, []byte(`"/devtools/`), []byte(`"`+prefix+`/devtools/`))
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			resp.ContentLength = int64(len(body))
+			resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			return nil
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
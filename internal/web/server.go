@@ -6,12 +6,18 @@ import (
 	"embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"mosoteach/internal/browser"
 	"mosoteach/internal/config"
 	"mosoteach/internal/models"
+	"mosoteach/internal/processor"
+	"mosoteach/internal/state"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,6 +26,8 @@ import (
 //go:embed static
 var staticFiles embed.FS
 
+const closingSoonWindow = 24 * time.Hour // 截止时间在此范围内的题库标记为即将截止
+
 // ProgressEvent 进度事件
 type ProgressEvent struct {
 	Type         string `json:"type"` // log, progress, complete, error
@@ -29,19 +37,21 @@ type ProgressEvent struct {
 	QuizName     string `json:"quizName,omitempty"`     // 当前题库名称
 	QuizProgress int    `json:"quizProgress,omitempty"` // 当前题库进度
 	QuizTotal    int    `json:"quizTotal,omitempty"`    // 题库总数
+	RunID        string `json:"runId,omitempty"`        // 运行 ID（用于查看失败产物）
+	Account      string `json:"account,omitempty"`      // 事件所属的账号ID，为空时为全局事件
 }
 
 // Server Web服务器
 type Server struct {
-	mu         sync.RWMutex
 	cfg        *config.Config
-	executor   *browser.BrowserExecutor
-	status     *Status
+	accounts   map[string]*accountRuntime // 账号ID -> 运行状态
+	accountsMu sync.Mutex
 	sseClients map[chan ProgressEvent]bool
 	sseMu      sync.RWMutex
-	cancelFunc context.CancelFunc
 	sessions   map[string]time.Time // 会话令牌 -> 过期时间
 	sessionMu  sync.RWMutex
+
+	unsubscribe func() // 取消订阅配置变化
 }
 
 // Status 当前状态
@@ -51,39 +61,69 @@ type Status struct {
 	Progress    int    `json:"progress"`
 	Total       int    `json:"total"`
 	CurrentTask string `json:"currentTask"`
+	RunID       string `json:"runId"` // 最近一次运行的 ID
+
+	Debug    *browser.DebugState `json:"debug,omitempty"`    // 调试运行状态
+	TimeLeft *int                `json:"timeLeft,omitempty"` // 限时测验剩余秒数
+	FastMode bool                `json:"fastMode,omitempty"` // 时间不足，已切换到快速模式
+
+	Errors []config.ValidationError `json:"errors,omitempty"` // 未运行时配置的验证错误
 }
 
-// NewServer 创建服务器
-func NewServer() *Server {
-	return &Server{
-		cfg: config.GetConfig(),
-		status: &Status{
-			Running: false,
-			Message: "就绪",
-		},
+// NewServer 创建服务器，cfg 需已加载
+func NewServer(cfg *config.Config) *Server {
+	s := &Server{
+		cfg:        cfg,
+		accounts:   make(map[string]*accountRuntime),
 		sseClients: make(map[chan ProgressEvent]bool),
 		sessions:   make(map[string]time.Time),
 	}
+	s.unsubscribe = cfg.Subscribe(s.onConfigReload)
+	return s
+}
+
+// onConfigReload 配置文件被修改并重新加载后调用
+func (s *Server) onConfigReload(previous, current config.ConfigFile) {
+	// 访问密码变化后已登录的浏览器需要重新输入密码
+	if current.WebPassword != previous.WebPassword {
+		s.clearSessions()
+	}
+	// 账号变化后旧的Cookie不再可用
+	s.reloadAccounts(previous, current)
+	s.sendSSEEvent(ProgressEvent{Type: "log", Message: "配置文件已修改，已重新加载"})
 }
 
-// Start 启动服务器
-func (s *Server) Start(port int) error {
+// Start 启动服务器，addr 为监听地址（如 :11451、127.0.0.1:8080）
+func (s *Server) Start(addr string) error {
 	mux := http.NewServeMux()
 
 	// API路由
 	mux.HandleFunc("/api/auth/check", s.handleAuthCheck)
 	mux.HandleFunc("/api/auth/login", s.handleAuthLogin)
+	mux.HandleFunc("/api/accounts", s.handleAccounts)
+	mux.HandleFunc("/api/accounts/{id}", s.handleAccount)
+	mux.HandleFunc("/api/accounts/{id}/inspector/{path...}", s.handleInspector)
 	mux.HandleFunc("/api/config", s.handleConfig)
 	mux.HandleFunc("/api/config/save", s.handleSaveConfig)
+	mux.HandleFunc("/api/config/validate", s.handleValidateConfig)
+	mux.HandleFunc("/api/config/export", s.handleConfigExport)
+	mux.HandleFunc("/api/config/import", s.handleConfigImport)
 	mux.HandleFunc("/api/models", s.handleModels)
 	mux.HandleFunc("/api/models/save", s.handleSaveModels)
 	mux.HandleFunc("/api/models/test", s.handleTestModel)
 	mux.HandleFunc("/api/quizzes", s.handleQuizzes)
 	mux.HandleFunc("/api/quizzes/cache", s.handleQuizzesCache)
+	mux.HandleFunc("/api/courses", s.handleCourses)
+	mux.HandleFunc("/api/courses/{id}", s.handleCourseFlags)
+	mux.HandleFunc("/api/courses/{id}/refresh", s.handleCourseRefresh)
 	mux.HandleFunc("/api/login", s.handleLogin)
 	mux.HandleFunc("/api/start", s.handleStart)
 	mux.HandleFunc("/api/stop", s.handleStop)
+	mux.HandleFunc("/api/debug/{action}", s.handleDebugAction)
 	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/runs", s.handleRuns)
+	mux.HandleFunc("/api/runs/{id}/artifacts", s.handleRunArtifacts)
+	mux.HandleFunc("/api/runs/{id}/artifacts/{name}", s.handleRunArtifactFile)
 	mux.HandleFunc("/api/events", s.handleSSE)
 	mux.HandleFunc("/api/settings/submit-delay", s.handleSubmitDelay)
 	mux.HandleFunc("/api/settings/web-password", s.handleWebPassword)
@@ -95,13 +135,24 @@ func (s *Server) Start(port int) error {
 	}
 	mux.Handle("/", http.FileServer(http.FS(staticFS)))
 
-	addr := fmt.Sprintf(":%d", port)
-	fmt.Printf("🚀 服务器已启动: http://localhost%s\n", addr)
+	host := addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	fmt.Printf("🚀 服务器已启动: http://%s\n", host)
 
 	// 使用 Basic Auth 中间件包装
 	return http.ListenAndServe(addr, s.authMiddleware(mux))
 }
 
+// Close 释放服务器持有的资源（关闭所有账号的常驻浏览器）
+func (s *Server) Close() {
+	s.unsubscribe()
+	for id := range s.runtimes() {
+		s.dropRuntime(id)
+	}
+}
+
 // authMiddleware Cookie 认证中间件
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -203,7 +254,7 @@ func (s *Server) handleAuthCheck(w http.ResponseWriter, r *http.Request) {
 	if password == "" {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"required": false,
+			"required":      false,
 			"authenticated": true,
 		})
 		return
@@ -215,7 +266,7 @@ func (s *Server) handleAuthCheck(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"required": true,
+		"required":      true,
 		"authenticated": authenticated,
 	})
 }
@@ -275,14 +326,28 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.cfg.Load()
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	// Cookie 预计过期时间（Unix 秒，0 表示未知）
+	var cookieExpires int64
+	if expiry := rt.cfg.CookieExpiry(); !expiry.IsZero() {
+		cookieExpires = expiry.Unix()
+	}
 
 	// 返回用户配置
+	account := rt.cfg.GetUserData()
 	response := map[string]interface{}{
-		"user_name":    s.cfg.UserData.UserName,
-		"has_password": s.cfg.UserData.Password != "",
-		"has_cookie":   s.cfg.UserData.Cookie != "",
-		"masked_user":  s.cfg.GetMaskedUsername(),
+		"account":        rt.id,
+		"user_name":      account.UserName,
+		"has_password":   account.Password != "",
+		"has_cookie":     rt.cfg.GetCookie() != "",
+		"cookie_expires": cookieExpires,
+		"masked_user":    rt.cfg.GetMaskedUsername(),
+		"listen":         s.cfg.GetListen(),
+		"sources":        s.cfg.GetSources(), // 每个配置项的来源：default / file / env / flag
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -297,6 +362,7 @@ func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
+		Account  string `json:"account"` // 账号ID，为空时使用查询参数或默认账号
 		UserName string `json:"user_name"`
 		Password string `json:"password"`
 	}
@@ -306,24 +372,68 @@ func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	if req.UserName != "" {
-		s.cfg.UserData.UserName = req.UserName
-	}
-	if req.Password != "" {
-		s.cfg.UserData.SetPassword(req.Password)
+	rt := s.requestRuntime(w, r, req.Account)
+	if rt == nil {
+		return
 	}
-	s.mu.Unlock()
 
-	if err := s.cfg.Save(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	accountChanged, err := rt.cfg.UpdateAccount(req.UserName, req.Password)
+	if err != nil {
+		writeSaveError(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	// 账号变更后旧的Cookie不再可用
+	if accountChanged {
+		if err := rt.logout(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "配置保存成功"})
 }
 
+// handleValidateConfig 验证账号的配置，返回所有字段级错误
+func (s *Server) handleValidateConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	errs := rt.cfg.Validate()
+	if errs == nil {
+		errs = []config.ValidationError{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"valid":  len(errs) == 0,
+		"errors": errs,
+	})
+}
+
+// writeSaveError 写入保存配置失败的响应：验证错误以 422 返回字段级错误列表，其他错误使用 status
+func writeSaveError(w http.ResponseWriter, err error, status int) {
+	var verrs config.ValidationErrors
+	if !errors.As(err, &verrs) {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": verrs[0].Message,
+		"errors":  verrs,
+	})
+}
+
 // handleModels 获取模型配置
 func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -331,17 +441,23 @@ func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.cfg.Load()
-
-	// 返回模型列表（隐藏API Key明文）
-	models := make([]map[string]interface{}, len(s.cfg.Models))
-	for i, m := range s.cfg.Models {
+	// 返回模型列表（隐藏API Key明文、额外请求头的值和代理密码）
+	saved := s.cfg.GetModels()
+	models := make([]map[string]interface{}, len(saved))
+	for i, m := range saved {
+		masked := config.MaskModelSecrets(m)
 		models[i] = map[string]interface{}{
-			"name":        m.Name,
-			"enabled":     m.Enabled,
-			"base_url":    m.BaseURL,
-			"model":       m.Model,
-			"has_api_key": m.APIKey != "",
+			"name":          m.Name,
+			"enabled":       m.Enabled,
+			"base_url":      m.BaseURL,
+			"model":         m.Model,
+			"has_api_key":   m.APIKey != "",
+			"temperature":   m.Temperature,
+			"max_tokens":    m.MaxTokens,
+			"timeout":       m.Timeout,
+			"extra_headers": masked.ExtraHeaders,
+			"extra_body":    m.ExtraBody,
+			"proxy":         masked.Proxy,
 		}
 	}
 
@@ -362,23 +478,11 @@ func (s *Server) handleSaveModels(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 合并API Key（如果新配置中为空则保留原有的）
-	s.mu.Lock()
-	for i := range models {
-		if models[i].APIKey == "" {
-			// 查找原有模型的API Key
-			for _, oldModel := range s.cfg.Models {
-				if oldModel.Name == models[i].Name {
-					models[i].APIKey = oldModel.APIKey
-					break
-				}
-			}
-		}
-	}
-	s.mu.Unlock()
+	// 为空的API Key、隐藏的请求头和代理密码保留原有的值
+	models = config.RestoreModelSecrets(models, s.cfg.GetModels())
 
 	if err := s.cfg.UpdateModels(models); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeSaveError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -399,16 +503,8 @@ func (s *Server) handleTestModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 如果没有传API Key，尝试从已保存的配置中获取
-	if req.APIKey == "" {
-		s.cfg.Load()
-		for _, m := range s.cfg.Models {
-			if m.Name == req.Name {
-				req.APIKey = m.APIKey
-				break
-			}
-		}
-	}
+	// 没有传API Key、请求头和代理密码隐藏时，从已保存的配置中获取
+	req = config.RestoreModelSecrets([]config.ModelConfig{req}, s.cfg.GetModels())[0]
 
 	// 验证必要字段
 	if req.BaseURL == "" || req.Model == "" || req.APIKey == "" {
@@ -420,9 +516,9 @@ func (s *Server) handleTestModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 创建模型并测试
+	// 创建模型并测试（超时使用模型配置的请求超时）
 	model := models.NewUnifiedModel(req)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), model.Timeout())
 	defer cancel()
 
 	answer, err := model.GetAnswer(ctx, "请回复：测试成功")
@@ -452,8 +548,11 @@ func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 
 	// 解析请求参数
 	var req struct {
-		QuizURL  string   `json:"quizUrl"`  // 可选：指定单个题库URL（兼容旧版）
-		QuizURLs []string `json:"quizUrls"` // 可选：指定多个题库URL
+		Quizzes  []processor.QuizRef `json:"quizzes"`  // 可选：指定题库（课程ID + 题库ID）
+		QuizURL  string              `json:"quizUrl"`  // 可选：指定单个题库URL（兼容旧版）
+		QuizURLs []string            `json:"quizUrls"` // 可选：指定多个题库URL（兼容旧版）
+		Debug    bool                `json:"debug"`    // 可选：调试模式（显示浏览器并单步执行）
+		Account  string              `json:"account"`  // 可选：账号ID（也可以用查询参数 account 指定）
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
 		// 忽略空 body 的情况
@@ -461,9 +560,34 @@ func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	if s.status.Running {
-		s.mu.Unlock()
+	rt := s.requestRuntime(w, r, req.Account)
+	if rt == nil {
+		return
+	}
+
+	// 旧版按地址指定的题库转换为题库ID，答题地址在运行时重新获取
+	refs := req.Quizzes
+	if req.QuizURL != "" {
+		req.QuizURLs = append(req.QuizURLs, req.QuizURL)
+	}
+	for _, quizURL := range req.QuizURLs {
+		ref, ok := rt.quizRefByURL(quizURL)
+		if !ok {
+			http.Error(w, "未找到题库，请先刷新题库列表: "+quizURL, http.StatusBadRequest)
+			return
+		}
+		refs = append(refs, ref)
+	}
+	for _, ref := range refs {
+		if ref.CourseID == "" || ref.QuizID == "" {
+			http.Error(w, "courseId 和 quizId 不能为空", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rt.mu.Lock()
+	if rt.status.Running {
+		rt.mu.Unlock()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
@@ -471,39 +595,79 @@ func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	s.status.Running = true
-	s.status.Message = "正在初始化..."
-	s.status.Progress = 0
-	s.mu.Unlock()
-
-	// 创建可取消的context
+	// 创建可取消的context，与运行状态一起设置，停止请求不会错过
 	ctx, cancel := context.WithCancel(context.Background())
-	s.cancelFunc = cancel
+	rt.status.Running = true
+	rt.status.Message = "正在初始化..."
+	rt.status.Progress = 0
+	rt.cancelFunc = cancel
+	rt.mu.Unlock()
+
+	// 运行结束或启动失败时重置运行状态
+	finish := func() {
+		cancel()
+		rt.mu.Lock()
+		rt.status.Running = false
+		rt.cancelFunc = nil
+		rt.executor = nil
+		rt.stepper = nil
+		rt.mu.Unlock()
+	}
+
+	rt.send(ProgressEvent{Type: "log", Message: "正在启动浏览器..."})
+
+	runID := browser.NewRunID()
+	var executor *browser.BrowserExecutor
+	var stepper *browser.Stepper
+	if req.Debug {
+		// 调试运行使用独立的有界面浏览器，不占用共享会话
+		stepper = browser.NewStepper()
+		executor = browser.NewBrowserExecutorWithCallback(rt.cfg, rt.progressCallback)
+		executor.SetStepper(stepper)
+		rt.send(ProgressEvent{Type: "log", Message: "调试模式: 每个阶段前会暂停，请点击「下一步」或「继续」"})
+	} else {
+		executor = browser.NewBrowserExecutorWithSession(rt.cfg, rt.session, rt.progressCallback)
+	}
+	executor.SetRunID(runID)
+	rt.mu.Lock()
+	rt.executor = executor
+	rt.stepper = stepper
+	rt.status.RunID = runID
+	rt.mu.Unlock()
+
+	// 回复前先启动浏览器，会话被其他任务占用时立即告知调用方
+	if err := executor.Start(ctx); err != nil {
+		finish()
+		if errors.Is(err, browser.ErrSessionBusy) {
+			writeSessionBusy(w)
+			return
+		}
+		msg := describeError(err)
+		rt.send(ProgressEvent{Type: "error", Message: msg, RunID: runID})
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
 
 	// 异步执行答题
 	go func() {
+		defer finish()
+
+		// 记录运行历史
+		run := config.RunRecord{ID: runID, StartedAt: time.Now().Unix()}
+		for _, ref := range refs {
+			run.Quizzes = append(run.Quizzes, config.QuizKey(ref.CourseID, ref.QuizID))
+		}
 		defer func() {
-			s.mu.Lock()
-			s.status.Running = false
-			s.cancelFunc = nil
-			s.executor = nil
-			s.mu.Unlock()
+			run.FinishedAt = time.Now().Unix()
+			if err := rt.cfg.AddRunRecord(run); err != nil {
+				slog.Debug("保存运行记录失败", "error", err)
+			}
 		}()
 
-		s.sendSSEEvent(ProgressEvent{Type: "log", Message: "正在启动浏览器..."})
-
-		executor := browser.NewBrowserExecutorWithCallback(s.progressCallback)
-		s.mu.Lock()
-		s.executor = executor
-		s.mu.Unlock()
-
 		var err error
-		if len(req.QuizURLs) > 0 {
-			// 答多个选中的题库
-			err = executor.RunMultipleQuizzes(ctx, req.QuizURLs)
-		} else if req.QuizURL != "" {
-			// 答单个指定题库（兼容旧版）
-			err = executor.RunSingleQuiz(ctx, req.QuizURL)
+		if len(refs) > 0 {
+			// 答选中的题库
+			err = executor.RunMultipleQuizzes(ctx, refs)
 		} else {
 			// 答所有题库
 			err = executor.RunWithContext(ctx)
@@ -513,27 +677,31 @@ func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 			// 区分取消和真正的错误
 			if ctx.Err() != nil {
 				// 用户取消 - 发送cancelled事件并重置进度
-				s.sendSSEEvent(ProgressEvent{Type: "cancelled", Message: "任务已取消", Progress: 0, Total: 0})
-				s.mu.Lock()
-				s.status.Message = "任务已取消"
-				s.status.Progress = 0
-				s.status.Total = 0
-				s.mu.Unlock()
+				run.Result, run.Message = state.RunCancelled, "任务已取消"
+				rt.send(ProgressEvent{Type: "cancelled", Message: "任务已取消", Progress: 0, Total: 0, RunID: runID})
+				rt.mu.Lock()
+				rt.status.Message = "任务已取消"
+				rt.status.Progress = 0
+				rt.status.Total = 0
+				rt.mu.Unlock()
 			} else {
 				// 真正的错误
-				s.sendSSEEvent(ProgressEvent{Type: "error", Message: fmt.Sprintf("错误: %v", err)})
-				s.mu.Lock()
-				s.status.Message = fmt.Sprintf("错误: %v", err)
-				s.mu.Unlock()
+				msg := describeError(err)
+				run.Result, run.Message = state.RunError, msg
+				rt.send(ProgressEvent{Type: "error", Message: msg, RunID: runID})
+				rt.mu.Lock()
+				rt.status.Message = msg
+				rt.mu.Unlock()
 			}
 			return
 		}
 
-		s.sendSSEEvent(ProgressEvent{Type: "complete", Message: "已完成所有题目"})
-		s.mu.Lock()
-		s.status.Message = "已完成所有题目"
-		s.status.Progress = s.status.Total
-		s.mu.Unlock()
+		run.Result = state.RunComplete
+		rt.send(ProgressEvent{Type: "complete", Message: "已完成所有题目", RunID: runID})
+		rt.mu.Lock()
+		rt.status.Message = "已完成所有题目"
+		rt.status.Progress = rt.status.Total
+		rt.mu.Unlock()
 	}()
 
 	w.Header().Set("Content-Type", "application/json")
@@ -543,31 +711,6 @@ func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// progressCallback 进度回调
-func (s *Server) progressCallback(event browser.ProgressEvent) {
-	s.mu.Lock()
-	s.status.Message = event.Message
-	if event.Total > 0 {
-		s.status.Total = event.Total
-	}
-	if event.Progress > 0 {
-		s.status.Progress = event.Progress
-	}
-	s.status.CurrentTask = event.Message
-	s.mu.Unlock()
-
-	// 转换为web包的ProgressEvent
-	s.sendSSEEvent(ProgressEvent{
-		Type:         event.Type,
-		Message:      event.Message,
-		Progress:     event.Progress,
-		Total:        event.Total,
-		QuizName:     event.QuizName,
-		QuizProgress: event.QuizProgress,
-		QuizTotal:    event.QuizTotal,
-	})
-}
-
 // handleStop 停止答题
 func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -575,20 +718,25 @@ func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	if s.cancelFunc != nil {
-		s.cancelFunc()
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	rt.mu.Lock()
+	if rt.cancelFunc != nil {
+		rt.cancelFunc()
 	}
 	// 显式关闭浏览器进程
-	if s.executor != nil {
-		s.executor.Stop()
-		s.executor = nil
+	if rt.executor != nil {
+		rt.executor.Stop()
+		rt.executor = nil
 	}
-	s.status.Running = false
-	s.status.Message = "已停止"
-	s.mu.Unlock()
+	rt.status.Running = false
+	rt.status.Message = "已停止"
+	rt.mu.Unlock()
 
-	s.sendSSEEvent(ProgressEvent{Type: "log", Message: "任务已停止"})
+	rt.send(ProgressEvent{Type: "log", Message: "任务已停止"})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]bool{"success": true})
@@ -601,36 +749,290 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.RLock()
-	status := *s.status
-	s.mu.RUnlock()
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	rt.mu.RLock()
+	status := *rt.status
+	stepper := rt.stepper
+	executor := rt.executor
+	rt.mu.RUnlock()
+
+	if executor != nil && status.Running {
+		if remaining, fast, ok := executor.TimeLeft(); ok {
+			seconds := max(int(remaining.Seconds()), 0)
+			status.TimeLeft = &seconds
+			status.FastMode = fast
+		}
+	}
+
+	if stepper != nil {
+		stepState := stepper.State()
+		// DevTools 端点通过服务器代理访问，需要同样的 Web 密码认证
+		if stepper.InspectorAddr() != "" {
+			stepState.InspectorURL = inspectorPath(rt.id) + "/json"
+		}
+		status.Debug = &stepState
+	}
 
 	// 如果不在运行中，动态检查就绪状态
 	if !status.Running {
-		status.Message = s.checkReadyStatus()
+		status.Message = rt.readyMessage()
+		status.Errors = rt.cfg.Validate()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
 
-// checkReadyStatus 检查系统就绪状态
-func (s *Server) checkReadyStatus() string {
-	s.cfg.Load()
-	_, message := s.cfg.IsReady()
-	return message
+// handleDebugAction 调试运行控制：next（下一步）、continue（继续）、pause（暂停）
+func (s *Server) handleDebugAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	rt.mu.RLock()
+	stepper := rt.stepper
+	rt.mu.RUnlock()
+
+	if stepper == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "当前没有调试运行",
+		})
+		return
+	}
+
+	switch r.PathValue("action") {
+	case "next":
+		stepper.Next()
+	case "continue":
+		stepper.Continue()
+	case "pause":
+		stepper.Pause()
+	default:
+		http.Error(w, "Unknown action", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"debug":   stepper.State(),
+	})
 }
 
-// handleQuizzes 获取题库列表（使用浏览器）
+// handleRuns 获取最近的答题运行记录（?limit=N，默认 20 条）
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	limit := 20
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rt.cfg.GetRunHistory(limit))
+}
+
+// handleRunArtifacts 列出账号某次运行保存的失败产物
+func (s *Server) handleRunArtifacts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	artifacts, err := browser.ListArtifacts(rt.id, r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(artifacts)
+}
+
+// handleRunArtifactFile 下载账号的单个失败产物文件
+func (s *Server) handleRunArtifactFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	dir, err := browser.RunArtifactDir(rt.id, r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 只允许访问目录下的文件名，防止路径穿越
+	name := r.PathValue("name")
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		http.Error(w, "Invalid artifact name", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, filepath.Join(dir, name))
+}
+
+// handleQuizzes 刷新题库列表（增量刷新，?full=1 时重新获取所有答题链接）
 func (s *Server) handleQuizzes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	s.mu.Lock()
-	if s.status.Running {
-		s.mu.Unlock()
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	rt.serveDiscovery(w, r, processor.DiscoverOptions{Full: r.URL.Query().Get("full") == "1"})
+}
+
+// handleCourseRefresh 只刷新单个课程的题库
+func (s *Server) handleCourseRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	rt.serveDiscovery(w, r, processor.DiscoverOptions{
+		CourseID: r.PathValue("id"),
+		Full:     r.URL.Query().Get("full") == "1",
+	})
+}
+
+// CourseResponse 课程信息（前端格式）
+type CourseResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Teacher     string `json:"teacher"`
+	Status      string `json:"status"`
+	LastRefresh int64  `json:"lastRefresh"`
+	Filter      string `json:"filter"`
+	Pinned      bool   `json:"pinned"`
+}
+
+// newCourseResponse 转换为前端格式
+func newCourseResponse(c config.Course) CourseResponse {
+	return CourseResponse{
+		ID:          c.ID,
+		Name:        c.Name,
+		Teacher:     c.Teacher,
+		Status:      c.Status,
+		LastRefresh: c.LastRefresh,
+		Filter:      c.Filter,
+		Pinned:      c.Pinned,
+	}
+}
+
+// handleCourses 获取保存的课程列表（?archived=1 时包括已归档的课程）
+func (s *Server) handleCourses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	archived := r.URL.Query().Get("archived") == "1"
+	courses := make([]CourseResponse, 0)
+	for _, c := range rt.cfg.GetCourses() {
+		if !archived && !c.IsOpen() {
+			continue
+		}
+		courses = append(courses, newCourseResponse(c))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(courses)
+}
+
+// handleCourseFlags 更新课程的包含/排除和置顶设置
+func (s *Server) handleCourseFlags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
+	}
+
+	var req struct {
+		Filter *string `json:"filter"`
+		Pinned *bool   `json:"pinned"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Filter != nil {
+		switch *req.Filter {
+		case config.CourseFilterDefault, config.CourseFilterInclude, config.CourseFilterExclude:
+		default:
+			http.Error(w, "filter 只能是 include、exclude 或空", http.StatusBadRequest)
+			return
+		}
+	}
+
+	course, err := rt.cfg.UpdateCourseFlags(r.PathValue("id"), req.Filter, req.Pinned)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"course":  newCourseResponse(course),
+	})
+}
+
+// serveDiscovery 登录后获取题库，更新缓存并返回完整的题库列表，客户端断开时停止获取
+func (rt *accountRuntime) serveDiscovery(w http.ResponseWriter, r *http.Request, opts processor.DiscoverOptions) {
+	rt.mu.Lock()
+	if rt.status.Running {
+		rt.mu.Unlock()
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusConflict)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -639,52 +1041,62 @@ func (s *Server) handleQuizzes(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	s.status.Running = true
-	s.status.Message = "正在获取题库..."
-	s.status.Progress = 0
-	s.status.Total = 0
-	s.mu.Unlock()
-
-	// 创建可取消的context
-	ctx, cancel := context.WithCancel(context.Background())
-	s.cancelFunc = cancel
+	// 创建可取消的context，与运行状态一起设置，停止请求不会错过
+	ctx, cancel := context.WithCancel(r.Context())
+	rt.status.Running = true
+	rt.status.Message = "正在获取题库..."
+	rt.status.Progress = 0
+	rt.status.Total = 0
+	rt.cancelFunc = cancel
+	rt.mu.Unlock()
 
 	defer func() {
-		s.mu.Lock()
-		s.status.Running = false
-		s.cancelFunc = nil
-		s.mu.Unlock()
+		cancel()
+		rt.mu.Lock()
+		rt.status.Running = false
+		rt.cancelFunc = nil
+		rt.mu.Unlock()
 	}()
 
-	s.sendSSEEvent(ProgressEvent{Type: "log", Message: "正在启动浏览器获取题库列表..."})
+	rt.send(ProgressEvent{Type: "log", Message: "正在启动浏览器获取题库列表..."})
 
-	// 使用浏览器获取题库
-	executor := browser.NewBrowserExecutorWithCallback(s.progressCallback)
+	// 启动浏览器会话（登录和浏览器方式获取题库都需要）
+	runID := browser.NewRunID()
+	executor := browser.NewBrowserExecutorWithSession(rt.cfg, rt.session, rt.progressCallback)
+	executor.SetRunID(runID)
+	rt.mu.Lock()
+	rt.status.RunID = runID
+	rt.mu.Unlock()
 	defer executor.Stop()
 
-	if err := executor.Start(); err != nil {
-		s.sendSSEEvent(ProgressEvent{Type: "error", Message: fmt.Sprintf("启动浏览器失败: %v", err)})
+	if err := executor.Start(ctx); err != nil {
+		if errors.Is(err, browser.ErrSessionBusy) {
+			writeSessionBusy(w)
+			return
+		}
+		rt.send(ProgressEvent{Type: "error", Message: fmt.Sprintf("启动浏览器失败: %v", err)})
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// 先登录
 	if err := executor.Login(); err != nil {
-		s.sendSSEEvent(ProgressEvent{Type: "error", Message: fmt.Sprintf("登录失败: %v", err)})
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		msg := describeError(err)
+		rt.send(ProgressEvent{Type: "error", Message: msg, RunID: runID})
+		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
 
-	// 获取题库（使用可取消的context）
-	quizzes, err := executor.FetchQuizzesByBrowserWithContext(ctx)
+	// 按配置的方式获取题库（默认 HTTP，失败时改用浏览器；使用可取消的context）
+	quizzes, err := executor.DiscoverQuizzes(ctx, opts)
 	if err != nil {
 		if ctx.Err() != nil {
-			s.sendSSEEvent(ProgressEvent{Type: "log", Message: "获取题库已取消"})
+			rt.send(ProgressEvent{Type: "log", Message: "获取题库已取消"})
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode([]interface{}{})
 			return
 		}
-		s.sendSSEEvent(ProgressEvent{Type: "error", Message: fmt.Sprintf("获取题库失败: %v", err)})
+		rt.send(ProgressEvent{Type: "error", Message: fmt.Sprintf("获取题库失败: %v", err)})
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -699,33 +1111,19 @@ func (s *Server) handleQuizzes(w http.ResponseWriter, r *http.Request) {
 			QuizID:     q.QuizID,
 			Name:       q.Name,
 			Completed:  q.Completed,
+			QuizMeta:   q.QuizMeta,
 		}
 	}
-	s.cfg.SaveCachedQuizzes(cachedQuizzes)
-
-	// 转换为JSON友好的格式
-	type QuizResponse struct {
-		URL        string `json:"url"`
-		Name       string `json:"name"`
-		CourseID   string `json:"courseId"`
-		CourseName string `json:"courseName"`
-		QuizID     string `json:"quizId"`
-		Completed  bool   `json:"completed"`
+	if opts.CourseID != "" {
+		rt.cfg.ReplaceCourseQuizzes(opts.CourseID, cachedQuizzes)
+	} else {
+		rt.cfg.SaveCachedQuizzes(cachedQuizzes)
 	}
 
-	var response []QuizResponse
-	for _, q := range quizzes {
-		response = append(response, QuizResponse{
-			URL:        q.URL,
-			Name:       q.Name,
-			CourseID:   q.CourseID,
-			CourseName: q.CourseName,
-			QuizID:     q.QuizID,
-			Completed:  q.Completed,
-		})
-	}
+	// 转换为JSON友好的格式
+	response := newQuizResponses(rt.cfg.GetCachedQuizzes())
 
-	s.sendSSEEvent(ProgressEvent{Type: "log", Message: fmt.Sprintf("找到 %d 个题库", len(response))})
+	rt.send(ProgressEvent{Type: "log", Message: fmt.Sprintf("找到 %d 个题库", len(quizzes))})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -738,31 +1136,53 @@ func (s *Server) handleQuizzesCache(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cachedQuizzes := s.cfg.GetCachedQuizzes()
-
-	type QuizResponse struct {
-		URL        string `json:"url"`
-		Name       string `json:"name"`
-		CourseID   string `json:"courseId"`
-		CourseName string `json:"courseName"`
-		QuizID     string `json:"quizId"`
-		Completed  bool   `json:"completed"`
+	rt := s.requestRuntime(w, r, "")
+	if rt == nil {
+		return
 	}
 
+	response := newQuizResponses(rt.cfg.GetCachedQuizzes())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// QuizResponse 题库列表响应
+type QuizResponse struct {
+	URL           string  `json:"url"`
+	Name          string  `json:"name"`
+	CourseID      string  `json:"courseId"`
+	CourseName    string  `json:"courseName"`
+	QuizID        string  `json:"quizId"`
+	Completed     bool    `json:"completed"`
+	Deadline      int64   `json:"deadline,omitempty"`      // 截止时间（Unix 秒）
+	AttemptsLeft  *int    `json:"attemptsLeft,omitempty"`  // 剩余作答次数
+	QuestionCount int     `json:"questionCount,omitempty"` // 题目数量
+	TotalScore    float64 `json:"totalScore,omitempty"`    // 总分
+	ClosingSoon   bool    `json:"closingSoon,omitempty"`   // 即将截止
+}
+
+// newQuizResponses 将缓存的题库转换为响应格式
+func newQuizResponses(quizzes []config.CachedQuiz) []QuizResponse {
+	now := time.Now()
 	var response []QuizResponse
-	for _, q := range cachedQuizzes {
+	for _, q := range quizzes {
+		deadline := time.Unix(q.Deadline, 0)
 		response = append(response, QuizResponse{
-			URL:        q.URL,
-			Name:       q.Name,
-			CourseID:   q.CourseID,
-			CourseName: q.CourseName,
-			QuizID:     q.QuizID,
-			Completed:  q.Completed,
+			URL:           q.URL,
+			Name:          q.Name,
+			CourseID:      q.CourseID,
+			CourseName:    q.CourseName,
+			QuizID:        q.QuizID,
+			Completed:     q.Completed,
+			Deadline:      q.Deadline,
+			AttemptsLeft:  q.AttemptsLeft,
+			QuestionCount: q.QuestionCount,
+			TotalScore:    q.TotalScore,
+			ClosingSoon:   q.Deadline > 0 && !q.Completed && deadline.After(now) && deadline.Sub(now) < closingSoonWindow,
 		})
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return response
 }
 
 // handleSSE SSE事件流
@@ -773,6 +1193,9 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	// 指定账号时只接收该账号和全局的事件
+	account := r.URL.Query().Get("account")
+
 	// 创建客户端通道
 	clientChan := make(chan ProgressEvent, 100)
 
@@ -802,6 +1225,9 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
+			if account != "" && event.Account != "" && event.Account != account {
+				continue
+			}
 			data, _ := json.Marshal(event)
 			fmt.Fprintf(w, "data: %s\n\n", data)
 			if f, ok := w.(http.Flusher); ok {
@@ -834,9 +1260,22 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	if s.status.Running {
-		s.mu.Unlock()
+	// 请求体可选，也可以用查询参数 account 指定账号
+	var req struct {
+		Account string `json:"account"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rt := s.requestRuntime(w, r, req.Account)
+	if rt == nil {
+		return
+	}
+
+	rt.mu.Lock()
+	if rt.status.Running {
+		rt.mu.Unlock()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
@@ -844,44 +1283,58 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	s.status.Running = true
-	s.status.Message = "正在登录..."
-	s.mu.Unlock()
+	rt.status.Running = true
+	rt.status.Message = "正在登录..."
+	rt.mu.Unlock()
+
+	rt.send(ProgressEvent{Type: "log", Message: "正在启动浏览器登录..."})
+
+	runID := browser.NewRunID()
+	executor := browser.NewBrowserExecutorWithSession(rt.cfg, rt.session, nil)
+	executor.SetRunID(runID)
+
+	// 先启动浏览器，标签页被占用时直接返回
+	if err := executor.Start(r.Context()); err != nil {
+		executor.Stop()
+		rt.mu.Lock()
+		rt.status.Running = false
+		rt.status.Message = fmt.Sprintf("启动浏览器失败: %v", err)
+		rt.mu.Unlock()
+		if errors.Is(err, browser.ErrSessionBusy) {
+			writeSessionBusy(w)
+			return
+		}
+		rt.send(ProgressEvent{Type: "error", Message: fmt.Sprintf("启动浏览器失败: %v", err)})
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rt.mu.Lock()
+	rt.status.RunID = runID
+	rt.mu.Unlock()
 
 	// 异步执行登录
 	go func() {
 		defer func() {
-			s.mu.Lock()
-			s.status.Running = false
-			s.mu.Unlock()
+			rt.mu.Lock()
+			rt.status.Running = false
+			rt.mu.Unlock()
 		}()
-
-		s.sendSSEEvent(ProgressEvent{Type: "log", Message: "正在启动浏览器登录..."})
-
-		executor := browser.NewBrowserExecutor()
 		defer executor.Stop()
 
-		// 先启动浏览器
-		if err := executor.Start(); err != nil {
-			s.sendSSEEvent(ProgressEvent{Type: "error", Message: fmt.Sprintf("启动浏览器失败: %v", err)})
-			s.mu.Lock()
-			s.status.Message = fmt.Sprintf("启动浏览器失败: %v", err)
-			s.mu.Unlock()
+		// 用户主动刷新时不使用保存的Cookie，强制重新登录
+		if err := executor.ForceLogin(); err != nil {
+			msg := describeError(err)
+			rt.send(ProgressEvent{Type: "error", Message: msg, RunID: runID})
+			rt.mu.Lock()
+			rt.status.Message = msg
+			rt.mu.Unlock()
 			return
 		}
 
-		if err := executor.Login(); err != nil {
-			s.sendSSEEvent(ProgressEvent{Type: "error", Message: fmt.Sprintf("登录失败: %v", err)})
-			s.mu.Lock()
-			s.status.Message = fmt.Sprintf("登录失败: %v", err)
-			s.mu.Unlock()
-			return
-		}
-
-		s.sendSSEEvent(ProgressEvent{Type: "complete", Message: "登录成功，Cookie已更新"})
-		s.mu.Lock()
-		s.status.Message = "登录成功"
-		s.mu.Unlock()
+		rt.send(ProgressEvent{Type: "complete", Message: "登录成功，Cookie已更新"})
+		rt.mu.Lock()
+		rt.status.Message = "登录成功"
+		rt.mu.Unlock()
 	}()
 
 	w.Header().Set("Content-Type", "application/json")
@@ -891,6 +1344,42 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// writeSessionBusy 浏览器标签页被其他任务占用
+func writeSessionBusy(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": browser.ErrSessionBusy.Error(),
+	})
+}
+
+// describeError 将错误转换为面向用户的提示，登录失败按类型给出处理建议
+func describeError(err error) string {
+	var msg string
+	switch {
+	case errors.Is(err, browser.ErrBadCredentials):
+		msg = "登录失败: 账号或密码错误，请在系统设置中检查"
+	case errors.Is(err, browser.ErrAccountLocked):
+		msg = "登录失败: 账号已被锁定或尝试过于频繁，请稍后再试"
+	case errors.Is(err, browser.ErrVerificationRequired):
+		msg = "登录失败: 云班课要求完成验证码或安全验证，请先在浏览器中手动登录一次"
+	case errors.Is(err, browser.ErrNetwork):
+		msg = "登录失败: 无法连接云班课，请检查网络"
+	case errors.Is(err, browser.ErrUnknownPage):
+		msg = "登录失败: 登录后页面状态异常，可能是页面结构已变化"
+	default:
+		return fmt.Sprintf("错误: %v", err)
+	}
+
+	// 快照路径只记录在服务端日志中，不返回给页面
+	var loginErr *browser.LoginError
+	if errors.As(err, &loginErr) && loginErr.Snapshot != "" {
+		slog.Info("已保存登录失败现场", "snapshot", loginErr.Snapshot)
+	}
+	return msg
+}
+
 // handleSubmitDelay 处理提交延迟配置
 func (s *Server) handleSubmitDelay(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -907,11 +1396,8 @@ func (s *Server) handleSubmitDelay(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid request", http.StatusBadRequest)
 			return
 		}
-		if req.SubmitDelay < 0 {
-			req.SubmitDelay = 0
-		}
 		if err := s.cfg.SetSubmitDelay(req.SubmitDelay); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeSaveError(w, err, http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")